@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// adventureCache keeps the adventure settings of every campaign, read on each request
+type adventureCache struct {
+	mu         sync.Mutex
+	adventures map[uint]db.Adventure
+}
+
+// adventure returns the adventure settings of a campaign, loaded from the database on the first call
+func (b *Bot) adventure(campaignID uint) (db.Adventure, error) {
+	b.adventures.mu.Lock()
+	defer b.adventures.mu.Unlock()
+
+	if a, ok := b.adventures.adventures[campaignID]; ok {
+		return a, nil
+	}
+
+	a, err := b.db.FetchAdventure(campaignID)
+	if err != nil {
+		return a, err
+	}
+
+	if b.adventures.adventures == nil {
+		b.adventures.adventures = map[uint]db.Adventure{}
+	}
+	b.adventures.adventures[campaignID] = a
+	return a, nil
+}
+
+// saveAdventure persists the adventure settings, then updates the cache
+func (b *Bot) saveAdventure(a *db.Adventure) error {
+	b.adventures.mu.Lock()
+	defer b.adventures.mu.Unlock()
+
+	if err := b.db.SaveAdventure(a); err != nil {
+		return err
+	}
+
+	if b.adventures.adventures == nil {
+		b.adventures.adventures = map[uint]db.Adventure{}
+	}
+	b.adventures.adventures[a.CampaignID] = *a
+	return nil
+}
+
+// startAdventureCmd starts the adventure in the current channel
+func (b *Bot) startAdventureCmd(req *Request) _Response {
+	a, err := b.adventure(req.Campaign.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch adventure: %w", err), req.T("adventure.error"))
+	}
+
+	a.ChannelID = req.ChannelID
+	a.StartedAt = b.now()
+	a.GameMaster = req.AuthorID
+	a.Status = db.AdventureRunning
+	if err := b.saveAdventure(&a); err != nil {
+		return simpleErr(fmt.Errorf("cannot set adventure: %w", err), req.T("adventure.save_error"))
+	}
+
+	return simpleResponse(b.campaignTranslator(req.Campaign).T("adventure.started"))
+}
+
+func (b *Bot) endAdventureCmd(req *Request) _Response {
+	a, err := b.adventure(req.Campaign.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch adventure: %w", err), req.T("adventure.error"))
+	}
+
+	if !a.IsRunning() {
+		return simpleResponse(req.T("adventure.not_running"))
+	}
+
+	a.Status = db.AdventureEnded
+	if err := b.saveAdventure(&a); err != nil {
+		return simpleErr(fmt.Errorf("cannot end adventure: %w", err), req.T("adventure.save_error"))
+	}
+
+	// the whole campaign reads the announce
+	return _Response{
+		msgs: []_Message{
+			{Channel: a.ChannelID, Message: b.campaignTranslator(req.Campaign).T("adventure.ended")},
+		},
+	}
+}
+
+func (b *Bot) adventureStatusCmd(req *Request) _Response {
+	a, err := b.adventure(req.Campaign.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch adventure: %w", err), req.T("adventure.error"))
+	}
+
+	return simpleResponse(writeAdventureStatus(req.tr, &a))
+}
+
+func writeAdventureStatus(tr i18n.Translator, a *db.Adventure) string {
+	vars := i18n.Vars{
+		"Channel":    util.ChannelIDToText(a.ChannelID),
+		"Date":       a.StartedAt.Format(tr.T("adventure.date_format")),
+		"GameMaster": util.DiscordIDToText(a.GameMaster),
+	}
+
+	switch a.Status {
+	case db.AdventureRunning:
+		return tr.T("adventure.running", vars)
+	case db.AdventureEnded:
+		return tr.T("adventure.over", vars)
+	}
+	return tr.T("adventure.none")
+}
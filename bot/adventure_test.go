@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"testing"
+)
+
+func TestAdventureLifecycle(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(10, "!adventure_status"), "Aucune aventure en cours.")
+	expectContains(t, h.sayOne(testGameMaster, "!end_adventure"), "No adventure in progress")
+	expectContains(t, h.sayOne(10, "!start_adventure"), "Vous n'êtes pas maître du jeu")
+
+	h.sayOne(testGameMaster, "!start_adventure")
+	expectContains(t, h.sayOne(10, "!adventure_status"),
+		"Aventure en cours dans <#adventure> depuis le 01/12/2020 à 20:00, menée par <@1>.")
+
+	expectContains(t, h.sayOne(testGameMaster, "!end_adventure"), "L'aventure est terminée")
+	expectContains(t, h.sayOne(10, "!adventure_status"), "L'aventure commencée le 01/12/2020 à 20:00 par <@1> est terminée.")
+	expectContains(t, h.sayOne(testGameMaster, "!shout Hello"), "!start_adventure")
+}
+
+func TestAdventureIsPersisted(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(testGameMaster, "!start_adventure")
+
+	// a restarted bot reads the adventure from the database
+	h.bot = &Bot{
+		Config:   h.bot.Config,
+		db:       h.bot.db,
+		bestiary: h.bot.bestiary,
+		items:    h.bot.items,
+		messages: h.bot.messages,
+		dice:     h.bot.dice,
+		now:      h.bot.now,
+	}
+	expectContains(t, h.sayOne(10, "!adventure_status"), "Aventure en cours dans <#adventure>")
+}
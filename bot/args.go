@@ -0,0 +1,326 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// _Range bounds an integer argument
+type _Range struct {
+	Min int
+	Max int
+}
+
+func atLeast(min int) *_Range {
+	return &_Range{Min: min, Max: math.MaxInt32}
+}
+
+func between(min int, max int) *_Range {
+	return &_Range{Min: min, Max: max}
+}
+
+// _Args are the arguments of a request, read along the schema of the command
+type _Args struct {
+	values map[string]interface{}
+}
+
+// Has tells if an optional argument or an option was given
+func (a _Args) Has(name string) bool {
+	_, ok := a.values[name]
+	return ok
+}
+
+// String returns a word, a text or an enum value, empty when missing
+func (a _Args) String(name string) string {
+	s, _ := a.values[name].(string)
+	return s
+}
+
+// Int returns an integer argument, 0 when missing
+func (a _Args) Int(name string) int {
+	return a.IntOr(name, 0)
+}
+
+// IntOr returns an integer argument, or def when missing
+func (a _Args) IntOr(name string, def int) int {
+	if n, ok := a.values[name].(int); ok {
+		return n
+	}
+	return def
+}
+
+// Flag tells if a --flag was given
+func (a _Args) Flag(name string) bool {
+	set, _ := a.values[name].(bool)
+	return set
+}
+
+// User returns the discord ID of a mentioned user, 0 when missing
+func (a _Args) User(name string) uint {
+	id, _ := a.values[name].(uint)
+	return id
+}
+
+// argError tells why the arguments do not match the schema of the command.
+// The reason is the args.<reason> message of the catalog.
+type argError struct {
+	arg    string
+	reason string
+	// vars complete the reason, like the bounds of a range
+	vars i18n.Vars
+}
+
+func (e *argError) Error() string {
+	if e.arg == "" {
+		return e.reason
+	}
+	return fmt.Sprintf("%s: %s %v", e.arg, e.reason, e.vars)
+}
+
+func (e *argError) Unwrap() error {
+	return errIllegalArgument
+}
+
+// describe writes the reason in the language of the translator
+func (e *argError) describe(tr i18n.Translator) string {
+	vars := i18n.Vars{}
+	if e.arg != "" {
+		vars["Arg"] = argLabel(tr, e.arg)
+	}
+	for name, value := range e.vars {
+		vars[name] = value
+	}
+	return tr.T("args."+e.reason, vars)
+}
+
+// _Token is a word of a command, or a quoted text
+type _Token struct {
+	value  string
+	quoted bool
+}
+
+// tokenize splits a text on spaces, keeping "quoted texts" together
+func tokenize(text string) ([]_Token, error) {
+	tokens := []_Token{}
+	current := strings.Builder{}
+	inToken, inQuote, quoted := false, false, false
+
+	for _, r := range text {
+		switch {
+		case r == '"':
+			if !inToken {
+				quoted = true
+			}
+			inToken = true
+			inQuote = !inQuote
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if inToken {
+				tokens = append(tokens, _Token{value: current.String(), quoted: quoted})
+				current.Reset()
+			}
+			inToken, quoted = false, false
+		default:
+			inToken = true
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuote {
+		return nil, &argError{reason: "quote"}
+	}
+	if inToken {
+		tokens = append(tokens, _Token{value: current.String(), quoted: quoted})
+	}
+	return tokens, nil
+}
+
+// parseArgs reads text along the arguments and the options of a schema.
+// Options are written key=value or --flag, anywhere. The arguments following a text are read from the end.
+func parseArgs(args []_Arg, options []_Arg, text string) (_Args, error) {
+	parsed := _Args{values: map[string]interface{}{}}
+
+	tokens, err := tokenize(text)
+	if err != nil {
+		return parsed, err
+	}
+
+	positional := []string{}
+	for _, token := range tokens {
+		if option, value, ok := findOption(options, token); ok {
+			if err := parsed.set(option, value); err != nil {
+				return parsed, err
+			}
+			continue
+		}
+		positional = append(positional, token.value)
+	}
+
+	textIndex := -1
+	for i := range args {
+		if args[i].Kind == argText {
+			textIndex = i
+			break
+		}
+	}
+
+	if textIndex < 0 {
+		if len(positional) > len(args) {
+			return parsed, &argError{reason: "too many"}
+		}
+		return parsed, parsed.setAll(args, positional)
+	}
+
+	if err := parsed.setAll(args[:textIndex], positional[:min(textIndex, len(positional))]); err != nil {
+		return parsed, err
+	}
+	if len(positional) < textIndex {
+		return parsed, nil
+	}
+	return parsed, parsed.setText(&args[textIndex], args[textIndex+1:], positional[textIndex:])
+}
+
+// setText reads the arguments trailing a text from the end, as many as possible
+func (a _Args) setText(text *_Arg, trailing []_Arg, tokens []string) error {
+	required := 0
+	for i := range trailing {
+		if !trailing[i].Optional {
+			required++
+		}
+	}
+	textMin := 1
+	if text.Optional {
+		textMin = 0
+	}
+
+	var lastErr error
+	for n := len(trailing); n >= required; n-- {
+		if len(tokens)-n < textMin {
+			continue
+		}
+
+		candidate := _Args{values: map[string]interface{}{}}
+		if err := candidate.setAll(trailing[:n], tokens[len(tokens)-n:]); err != nil {
+			// a number out of range is not part of the text
+			var argErr *argError
+			if errors.As(err, &argErr) && (argErr.reason == "range" || argErr.reason == "min") {
+				return err
+			}
+			if lastErr == nil {
+				lastErr = err
+			}
+			continue
+		}
+
+		for k, v := range candidate.values {
+			a.values[k] = v
+		}
+		if words := tokens[:len(tokens)-n]; len(words) > 0 {
+			a.values[text.Name] = strings.Join(words, " ")
+		}
+		return nil
+	}
+
+	if lastErr != nil {
+		return lastErr
+	}
+	if len(tokens) < textMin {
+		return &argError{arg: text.Name, reason: "missing"}
+	}
+	if len(tokens) < textMin+required {
+		return &argError{arg: trailing[len(tokens)-textMin].Name, reason: "missing"}
+	}
+	return &argError{reason: "too many"}
+}
+
+// setAll reads positional arguments, one token each
+func (a _Args) setAll(args []_Arg, tokens []string) error {
+	for i := range args {
+		if i >= len(tokens) {
+			if !args[i].Optional {
+				return &argError{arg: args[i].Name, reason: "missing"}
+			}
+			continue
+		}
+		if err := a.set(&args[i], tokens[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set reads the value of an argument along its kind
+func (a _Args) set(arg *_Arg, value string) error {
+	switch arg.Kind {
+	case argInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return &argError{arg: arg.Name, reason: "int"}
+		}
+		if arg.Range != nil && (n < arg.Range.Min || n > arg.Range.Max) {
+			if arg.Range.Max == math.MaxInt32 {
+				return &argError{arg: arg.Name, reason: "min", vars: i18n.Vars{"Min": arg.Range.Min}}
+			}
+			return &argError{arg: arg.Name, reason: "range", vars: i18n.Vars{"Min": arg.Range.Min, "Max": arg.Range.Max}}
+		}
+		a.values[arg.Name] = n
+	case argMention:
+		id, err := util.TextToDiscordID(value)
+		if err != nil {
+			return &argError{arg: arg.Name, reason: "mention"}
+		}
+		a.values[arg.Name] = id
+	case argFlag:
+		a.values[arg.Name] = strings.EqualFold(value, "true")
+	case argEnum:
+		for _, choice := range arg.Choices {
+			if strings.EqualFold(choice, value) {
+				a.values[arg.Name] = choice
+				return nil
+			}
+		}
+		return &argError{arg: arg.Name, reason: "enum", vars: i18n.Vars{"Choices": strings.Join(arg.Choices, ", ")}}
+	default:
+		a.values[arg.Name] = value
+	}
+	return nil
+}
+
+// findOption matches a key=value or a --flag token with an option
+func findOption(options []_Arg, token _Token) (*_Arg, string, bool) {
+	if token.quoted {
+		return nil, "", false
+	}
+
+	if flag := strings.TrimPrefix(token.value, "--"); flag != token.value {
+		for i := range options {
+			if options[i].Kind == argFlag && strings.EqualFold(options[i].Name, flag) {
+				return &options[i], "true", true
+			}
+		}
+		return nil, "", false
+	}
+
+	parts := strings.SplitN(token.value, "=", 2)
+	if len(parts) != 2 {
+		return nil, "", false
+	}
+	for i := range options {
+		if strings.EqualFold(options[i].Name, parts[0]) {
+			return &options[i], parts[1], true
+		}
+	}
+	return nil, "", false
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize(`  sword  "Rat king" xp=10 name="Big Boss"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []_Token{{"sword", false}, {"Rat king", true}, {"xp=10", false}, {"name=Big Boss", false}}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("token %d: expected %v, got %v", i, expected[i], tokens[i])
+		}
+	}
+
+	if _, err := tokenize(`"Rat king`); !errors.Is(err, errIllegalArgument) {
+		t.Errorf("expected an unclosed quote error, got %v", err)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	schema := []_Arg{
+		{Name: "item", Kind: argText},
+		{Name: "price", Kind: argInt, Range: atLeast(0)},
+		{Name: "stock", Kind: argInt, Optional: true, Range: atLeast(0)},
+	}
+
+	args, err := parseArgs(schema, nil, "potion of 3 wishes 12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.String("item") != "potion of 3 wishes" || args.Int("price") != 12 || args.Has("stock") {
+		t.Errorf("unexpected arguments %v", args.values)
+	}
+
+	args, err = parseArgs(schema, nil, "potion 12 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.String("item") != "potion" || args.Int("price") != 12 || args.Int("stock") != 5 {
+		t.Errorf("unexpected arguments %v", args.values)
+	}
+
+	cases := []struct {
+		text   string
+		reason string
+	}{
+		{"", "missing"},
+		{"potion", "missing"},
+		{"potion twelve", "int"},
+		{"potion -1", "min"},
+		{"potion 1 -1", "min"},
+		{`"potion 1`, "quote"},
+	}
+	for _, c := range cases {
+		_, err := parseArgs(schema, nil, c.text)
+		var argErr *argError
+		if !errors.As(err, &argErr) || argErr.reason != c.reason {
+			t.Errorf("%q: expected a %q error, got %v", c.text, c.reason, err)
+		}
+	}
+}
+
+func TestParseArgsKinds(t *testing.T) {
+	schema := []_Arg{
+		{Name: "action", Kind: argEnum, Choices: []string{"add", "remove"}},
+		{Name: "@player", Kind: argMention},
+	}
+	options := []_Arg{{Name: "count", Kind: argInt, Range: between(1, 3)}, {Name: "dry-run", Kind: argFlag}}
+
+	args, err := parseArgs(schema, options, "ADD count=2 <@!42>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.String("action") != "add" || args.User("@player") != 42 || args.Int("count") != 2 || args.Flag("dry-run") {
+		t.Errorf("unexpected arguments %v", args.values)
+	}
+
+	args, err = parseArgs(schema, options, "add --DRY-RUN <@42>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !args.Flag("dry-run") || args.User("@player") != 42 {
+		t.Errorf("unexpected arguments %v", args.values)
+	}
+
+	cases := []struct {
+		text   string
+		reason string
+	}{
+		{"promote <@42>", "enum"},
+		{"add Bob", "mention"},
+		{"add <@42> count=4", "range"},
+		{"add <@42> <@43>", "too many"},
+		{"add <@42> --force", "too many"},
+	}
+	for _, c := range cases {
+		_, err := parseArgs(schema, options, c.text)
+		var argErr *argError
+		if !errors.As(err, &argErr) || argErr.reason != c.reason {
+			t.Errorf("%q: expected a %q error, got %v", c.text, c.reason, err)
+		}
+	}
+}
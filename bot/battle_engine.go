@@ -1,42 +1,88 @@
 package bot
 
 import (
-	"math"
-	"math/rand"
+	"fmt"
 	"strconv"
 
-	"gorm.io/gorm"
-
 	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
 	"github.com/vincent-heng/discord-airpgbot/bot/util"
 )
 
-func (b *Bot) attackCurrentMonster(characterID uint) (string, error) {
+const hitStaminaCost = 10
+
+// classAction is the attack of a class, telling if the monster is defeated
+type classAction func(tx db.Store, tr i18n.Translator, attacker *db.Character, monster *db.Monster) (bool, string, error)
+
+// spendStamina regenerates the character, then pays for an action
+func (b *Bot) spendStamina(tx db.Store, character *db.Character, cost int) error {
+	character.Regenerate(b.progression, b.now())
+
+	if character.Stamina < cost {
+		return fmt.Errorf("%d stamina needed, %d left: %w", cost, character.Stamina, errNotEnoughStamina)
+	}
+	character.Stamina -= cost
+
+	return tx.UpdateCharacter(character, db.RegenColumns...)
+}
+
+// attackMonster makes the character of the user attack a monster of the campaign encounter, see db.FindTarget.
+// The report is written by tr.
+func (b *Bot) attackMonster(tr i18n.Translator, campaign *db.Campaign, userID uint, target string) (string, error) {
 	tx := b.db.Begin()
 	defer tx.Rollback()
 
-	attacker, monster, err := tx.GetParticipants(characterID)
+	attacker, monster, err := tx.GetParticipants(campaign.ID, userID, target)
 	if err != nil {
 		return "", err
 	}
 
-	endOfFight := false
-	actionReport := ""
+	if attacker.IsKnockedOut() {
+		return "", errKnockedOut
+	}
+
+	// an unknown class cannot attack, nor pay for it
+	var action classAction
 	switch attacker.Class {
-	case "Combattant":
-		endOfFight, actionReport, err = b.triggerFighterAction(tx, attacker, monster)
-		if err != nil {
-			return "", err
-		}
+	case db.ClassFighter:
+		action = b.triggerFighterAction
+	case db.ClassMage:
+		action = b.triggerMageAction
+	case db.ClassHealer:
+		action = b.triggerHealerAction
+	default:
+		return "", fmt.Errorf("class %q: %w", attacker.Class, errUnknownClass)
 	}
 
-	// Add character to battle participation
-	if e := tx.Model(monster).Association("Participants").Append(attacker); e != nil {
+	if err := b.spendStamina(tx, attacker, hitStaminaCost); err != nil {
+		return "", err
+	}
+
+	hpBefore := monster.CurrentHp
+	endOfFight, actionReport, err := action(tx, tr, attacker, monster)
+	if err != nil {
+		return "", err
+	}
+
+	// Add character to battle participation, with the HP actually taken from the monster
+	damage := hpBefore
+	if monster.CurrentHp > 0 {
+		damage -= monster.CurrentHp
+	}
+	if e := tx.AddParticipant(monster, attacker, damage); e != nil {
 		return "", e
 	}
 
 	if endOfFight { // Target defeated
-		report, err := b.computeVictory(tx, monster)
+		report, err := b.computeVictory(tx, tr, campaign.Split(), monster, attacker)
+		if err != nil {
+			return "", err
+		}
+
+		actionReport += report
+	} else {
+		report, err := b.triggerMonsterAction(tx, tr, monster)
 		if err != nil {
 			return "", err
 		}
@@ -44,83 +90,301 @@ func (b *Bot) attackCurrentMonster(characterID uint) (string, error) {
 		actionReport += report
 	}
 
-	return actionReport, tx.Commit().Error
+	return actionReport, tx.Commit()
 }
 
-func parseLevel(experience int) int {
-	floatExperience := float64(experience)
-	floatLevel := (-1 + math.Sqrt(1+2*floatExperience/25)) / 2
-	roundLevel := int(floatLevel) + 1 // 99 XP -> 0.9 floatLevel -> 1 roundLevel
-	return roundLevel
+// healCharacter restores the character HP. Resting is only allowed when no monster is around,
+// unlike a revive.
+func (b *Bot) healCharacter(campaignID uint, userID uint, revive bool) (db.Character, error) {
+	tx := b.db.Begin()
+	defer tx.Rollback()
+
+	character, err := tx.FetchCharacterInfo(campaignID, userID)
+	if err != nil {
+		return character, err
+	}
+
+	if !revive {
+		monsters, err := tx.FetchMonsters(campaignID)
+		if err != nil {
+			return character, err
+		}
+		if len(monsters) > 0 {
+			return character, errFightInProgress
+		}
+	}
+
+	character.Regenerate(b.progression, b.now())
+	character.CurrentHp = character.GetMaxHP(b.progression)
+	if err := tx.UpdateCharacter(&character, db.RegenColumns...); err != nil {
+		return character, err
+	}
+
+	return character, tx.Commit()
 }
 
-func (b *Bot) computeVictory(tx *db.DB, monsterTarget *db.Monster) (string, error) {
-	report := "L'adversaire est vaincu ! Le combat rapporte " +
-		strconv.Itoa(monsterTarget.Experience) +
-		" points d'expérience partagés entre :\n"
+// computeVictory shares the rewards of monsterTarget between the participants along the split strategy,
+// killer having struck the last blow
+func (b *Bot) computeVictory(tx db.Store, tr i18n.Translator, split string, monsterTarget *db.Monster,
+	killer *db.Character) (string, error) {
+	rewards := tr.N("victory.experience", monsterTarget.Experience)
+	if monsterTarget.Gold > 0 {
+		rewards = tr.T("victory.and", i18n.Vars{"Experience": rewards, "Gold": tr.N("victory.gold", monsterTarget.Gold)})
+	}
+	report := tr.T("victory.title", i18n.Vars{"Rewards": rewards}) + "\n"
 
 	// Gain XP for every participants
-	var participants []db.Character
-	tx.Model(monsterTarget).Association("Participants").Find(&participants)
+	participants, err := tx.FetchParticipants(monsterTarget)
+	if err != nil {
+		return "", err
+	}
+
+	participations, err := tx.FetchParticipations(monsterTarget)
+	if err != nil {
+		return "", err
+	}
+	damage := map[uint]int{}
+	for _, p := range participations {
+		damage[p.CharacterID] = p.Damage
+	}
 
-	sharedExperience := (monsterTarget.Experience) / len(participants)
+	contributions := make([]contribution, len(participants))
+	for i := range participants {
+		contributions[i] = contribution{damage: damage[participants[i].ID], lastHit: participants[i].ID == killer.ID}
+	}
+	experience := rewardShares(split, monsterTarget.Experience, contributions)
+	gold := rewardShares(split, monsterTarget.Gold, contributions)
 
 	for i := range participants {
 		participant := participants[i]
 
-		report += "- " + util.DiscordIDToText(participant.ID)
-		participant.Experience = participant.Experience + sharedExperience
-		newLevel := parseLevel(participant.Experience)
+		share := i18n.Vars{"Player": util.DiscordIDToText(participant.UserID), "Experience": experience[i], "Gold": gold[i]}
+		if monsterTarget.Gold > 0 {
+			report += "- " + tr.T("victory.share_gold", share)
+		} else {
+			report += "- " + tr.T("victory.share", share)
+		}
+		participant.Experience = participant.Experience + experience[i]
+		newLevel := b.progression.Level(participant.Experience)
 		if participant.Level < newLevel {
 			nbLevelUps := newLevel - participant.Level
-			report += ": Gain de niveau ! "
-			if nbLevelUps > 1 {
-				report += " x" + strconv.Itoa(nbLevelUps)
-			}
+			report += tr.N("victory.level_up", nbLevelUps)
 			participant.Level = newLevel
-			participant.SkillPoints = participant.SkillPoints + nbLevelUps*5
+			participant.SkillPoints = participant.SkillPoints + b.progression.SkillPoints(nbLevelUps)
 		}
 
-		if err := tx.Save(&participant).Error; err != nil {
+		if err := tx.UpdateCharacter(&participant, "experience", "level", "skill_points"); err != nil {
 			return "", err
 		}
+		if gold[i] > 0 {
+			if err := tx.AddGold(participant.ID, gold[i]); err != nil {
+				return "", err
+			}
+		}
 
 		report += "\n"
 	}
 
-	return report, nil
+	loot, err := b.distributeLoot(tx, tr, monsterTarget, participants, rewardWeights(split, contributions))
+	if err != nil {
+		return "", err
+	}
+
+	return report + loot, nil
 }
 
-func (b *Bot) triggerFighterAction(tx *db.DB, attacker *db.Character, monster *db.Monster) (bool, string, error) {
-	agilityBonus := rand.Intn(attacker.Agility*2 + 1) //nolint:gosec
-	hitPoints := attacker.Strength + agilityBonus
+func (b *Bot) triggerFighterAction(tx db.Store, tr i18n.Translator, attacker *db.Character, monster *db.Monster) (bool, string, error) {
+	agilityBonus, err := b.roll(tx, monster, attacker.ID, attacker.Agility*2+1)
+	if err != nil {
+		return false, "", err
+	}
+	weaponBonus, _, err := b.equipmentBonus(tx, attacker.ID)
+	if err != nil {
+		return false, "", err
+	}
+	hitPoints := attacker.Strength + weaponBonus + agilityBonus
 	damageReduction := monster.Agility
 	damage := hitPoints - damageReduction
 	if damage <= 0 { // At least 1 damage
 		damage = 1
 	}
-	monster.CurrentHp = monster.CurrentHp - damage
-
-	if e := tx.Model(&db.Monster{Model: gorm.Model{ID: monster.ID}}).Update("current_hp", monster.CurrentHp).Error; e != nil {
-		return false, "", e
+	endOfFight, err := damageMonster(tx, monster, db.BattleEvent{
+		Action:  db.ActionStrike,
+		ActorID: attacker.ID,
+		Roll:    agilityBonus,
+		Damage:  damage,
+	})
+	if err != nil {
+		return false, "", err
 	}
 
-	endOfFight := monster.CurrentHp <= 0
-	actionReport := writeFighterActionReport(attacker, monster, damage, agilityBonus)
+	actionReport := writeFighterActionReport(tr, attacker, monster, damage, agilityBonus, weaponBonus)
 	return endOfFight, actionReport, nil
 }
 
-func writeFighterActionReport(attacker *db.Character, monster *db.Monster, damage int, agilityBonus int) string {
-	return "**" +
-		util.DiscordIDToText(attacker.ID) +
-		"** inflige " +
-		strconv.Itoa(damage) +
-		" (" +
-		strconv.Itoa(attacker.Strength) +
-		"+" + strconv.Itoa(agilityBonus) +
-		"-" +
-		strconv.Itoa(monster.Agility) +
-		") points de dégâts à **" +
-		monster.Name +
-		"**.\n"
+// damageMonster lowers the monster HP and logs the attack, then tells if the monster is defeated
+func damageMonster(tx db.Store, monster *db.Monster, attack db.BattleEvent) (bool, error) {
+	monster.CurrentHp = monster.CurrentHp - attack.Damage
+
+	if e := tx.UpdateMonsterHP(monster); e != nil {
+		return false, e
+	}
+
+	attack.HP = monster.CurrentHp
+	if attack.HP < 0 {
+		attack.HP = 0
+	}
+	if e := tx.RecordEvent(monster, attack); e != nil {
+		return false, e
+	}
+
+	return monster.CurrentHp <= 0, nil
+}
+
+// triggerMonsterAction makes the monster retaliate against one of the fighters still standing
+func (b *Bot) triggerMonsterAction(tx db.Store, tr i18n.Translator, monster *db.Monster) (string, error) {
+	participants, err := tx.FetchParticipants(monster)
+	if err != nil {
+		return "", err
+	}
+
+	targets := []*db.Character{}
+	for i := range participants {
+		if !participants[i].IsKnockedOut() {
+			targets = append(targets, &participants[i])
+		}
+	}
+	if len(targets) == 0 {
+		return "", nil
+	}
+
+	target := targets[0]
+	if len(targets) > 1 {
+		targetIndex, err := b.roll(tx, monster, 0, len(targets))
+		if err != nil {
+			return "", err
+		}
+		target = targets[targetIndex]
+	}
+	target.Regenerate(b.progression, b.now())
+
+	agilityBonus, err := b.roll(tx, monster, 0, monster.Agility*2+1)
+	if err != nil {
+		return "", err
+	}
+	_, armorBonus, err := b.equipmentBonus(tx, target.ID)
+	if err != nil {
+		return "", err
+	}
+	damage := monster.Strength + agilityBonus - target.Agility - armorBonus
+	if damage <= 0 { // At least 1 damage
+		damage = 1
+	}
+	target.CurrentHp = target.CurrentHp - damage
+	if target.CurrentHp < 0 {
+		target.CurrentHp = 0
+	}
+
+	if err := tx.UpdateCharacter(target, db.RegenColumns...); err != nil {
+		return "", err
+	}
+
+	if err := tx.RecordEvent(monster, db.BattleEvent{
+		Action:   db.ActionRiposte,
+		TargetID: target.ID,
+		Roll:     agilityBonus,
+		Damage:   damage,
+		HP:       target.CurrentHp,
+	}); err != nil {
+		return "", err
+	}
+
+	return writeMonsterActionReport(tr, b.progression, monster, target, damage, agilityBonus, armorBonus), nil
+}
+
+func writeMonsterActionReport(tr i18n.Translator, curve *progression.Curve, monster *db.Monster, target *db.Character,
+	damage int, agilityBonus int, armorBonus int) string {
+	report := tr.T("battle.monster", i18n.Vars{
+		"Monster": monster.Name,
+		"Damage":  damage,
+		"Formula": strconv.Itoa(monster.Strength) + "+" + strconv.Itoa(agilityBonus) + "-" +
+			strconv.Itoa(target.Agility) + writeMalus(armorBonus),
+		"Player": util.DiscordIDToText(target.UserID),
+		"HP":     target.CurrentHp,
+		"MaxHP":  target.GetMaxHP(curve),
+	}) + "\n"
+
+	if target.IsKnockedOut() {
+		report += tr.T("battle.knocked_out", i18n.Vars{"Player": util.DiscordIDToText(target.UserID)}) + "\n"
+	}
+
+	return report
+}
+
+// writeBonus writes an optional bonus of a damage formula
+func writeBonus(bonus int) string {
+	if bonus == 0 {
+		return ""
+	}
+	return "+" + strconv.Itoa(bonus)
+}
+
+// writeMalus writes an optional malus of a damage formula
+func writeMalus(malus int) string {
+	if malus == 0 {
+		return ""
+	}
+	return "-" + strconv.Itoa(malus)
+}
+
+func writeFighterActionReport(tr i18n.Translator, attacker *db.Character, monster *db.Monster,
+	damage int, agilityBonus int, weaponBonus int) string {
+	return tr.T("battle.fighter", i18n.Vars{
+		"Player": util.DiscordIDToText(attacker.UserID),
+		"Damage": damage,
+		"Formula": strconv.Itoa(attacker.Strength) + writeBonus(weaponBonus) + "+" + strconv.Itoa(agilityBonus) +
+			"-" + strconv.Itoa(monster.Agility),
+		"Monster": monster.Name,
+	}) + "\n"
+}
+
+// writeReplay draws again every roll of the fight from its seed, and checks them against the recorded ones
+func writeReplay(tr i18n.Translator, monster *db.Monster, rolls []db.BattleRoll, participants []db.Character) string {
+	players := map[uint]uint{}
+	for i := range participants {
+		players[participants[i].ID] = participants[i].UserID
+	}
+
+	report := tr.N("replay.title", len(rolls), i18n.Vars{
+		"ID":      monster.ID,
+		"Monster": monster.Name,
+		"Seed":    strconv.FormatInt(monster.Seed, 10),
+	}) + "\n"
+
+	for i := range rolls {
+		roll := &rolls[i]
+		replayed := rollDie(monster.Seed, roll.RollIndex, roll.Sides)
+
+		roller := monster.Name
+		if roll.CharacterID != 0 {
+			roller = util.DiscordIDToText(players[roll.CharacterID])
+		}
+
+		report += tr.T("replay.roll", i18n.Vars{
+			"Index":  roll.RollIndex + 1,
+			"Roller": roller,
+			"Result": replayed,
+			"Max":    roll.Sides - 1,
+		})
+		if replayed != roll.Result {
+			report += tr.T("replay.mismatch", i18n.Vars{"Result": roll.Result})
+		}
+		report += "\n"
+	}
+
+	if monster.CurrentHp > 0 {
+		report += tr.T("replay.in_progress") + "\n"
+	}
+
+	return report
 }
@@ -0,0 +1,331 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+func TestHitWithoutMonster(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+
+	expectContains(t, h.sayOne(10, "!hit"), "Il n'y a plus de monstre")
+}
+
+func TestFighterDamage(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(10, "!str 5")
+	h.sayOne(testGameMaster, "!spawn Dummy_0_0_2_1_1000")
+
+	for i := 0; i < 20; i++ {
+		// the ripostes deal at least 1 damage, and every hit costs stamina
+		c := h.character(10)
+		c.CurrentHp = c.GetMaxHP(h.bot.progression)
+		c.Stamina = db.MaxStamina
+		if err := h.store.SaveCharacter(&c); err != nil {
+			t.Fatal(err)
+		}
+
+		before, err := h.store.FetchMonsterInfo(h.campaign())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		report := h.sayOne(10, "!hit")
+
+		after, err := h.store.FetchMonsterInfo(h.campaign())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// strength 6 + agility bonus in [0,2] - monster agility 2
+		damage := before.CurrentHp - after.CurrentHp
+		if damage < 4 || damage > 6 {
+			t.Fatalf("unexpected damage %d: %q", damage, report)
+		}
+		expectContains(t, report, "inflige ")
+		expectContains(t, report, "points de dégâts à **Dummy**")
+	}
+}
+
+func TestFighterDealsAtLeastOneDamage(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Turtle_0_1_50_1_10")
+
+	expectContains(t, h.sayOne(10, "!hit"), "inflige 1 (")
+
+	m, err := h.store.FetchMonsterInfo(h.campaign())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CurrentHp != m.GetMaxHP()-1 {
+		t.Errorf("expected 1 damage, monster has %d HP", m.CurrentHp)
+	}
+}
+
+// Three players join, the GM spawns a monster, players hit until it dies,
+// experience is split and everybody levels up
+func TestBattleScenario(t *testing.T) {
+	h := newHarness(t)
+	players := []uint{11, 12, 13}
+
+	h.sayOne(testGameMaster, "!start_adventure")
+	for _, p := range players {
+		h.sayOne(p, "!join_adventure")
+	}
+	h.sayOne(testGameMaster, "!spawn Goblin_300_1_1_1_1")
+
+	victory := ""
+	for turn := 0; turn < 100 && victory == ""; turn++ {
+		report := h.sayOne(players[turn%len(players)], "!hit")
+		if strings.Contains(report, "L'adversaire est vaincu !") {
+			victory = report
+		}
+	}
+	if victory == "" {
+		t.Fatal("the goblin never died")
+	}
+
+	expectContains(t, victory, "Le combat rapporte 300 points d'expérience")
+	for _, p := range players {
+		c := h.character(p)
+		if c.Experience != 100 || c.Level != 2 || c.SkillPoints != 10 {
+			t.Errorf("unexpected progression for %d: %+v", p, c)
+		}
+		expectContains(t, victory, "- "+util.DiscordIDToText(p)+" (+100 XP): Gain de niveau !")
+	}
+
+	expectContains(t, h.sayOne(11, "!watch"), "Il n'y a plus de monstre")
+	expectContains(t, h.sayOne(11, "!hit"), "Il n'y a plus de monstre")
+}
+
+func TestMultipleLevelUps(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Dragon_600_1_1_1_-9")
+
+	report := h.sayOne(10, "!hit")
+	expectContains(t, report, "<@10> (+600 XP): Gain de niveau !  x3")
+
+	c := h.character(10)
+	if c.Level != 4 || c.SkillPoints != 20 {
+		t.Errorf("unexpected progression %+v", c)
+	}
+}
+
+func TestXP(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	expectContains(t, h.sayOne(10, "!xp"), "<@10> : niveau 1, 0 XP\nNiveau 2 à 100 XP, encore 100 XP `░░░░░░░░░░`")
+
+	h.sayOne(testGameMaster, "!spawn Rat_150_1_1_1_-9")
+	h.sayOne(10, "!hit")
+	expectContains(t, h.sayOne(10, "!xp"), "<@10> : niveau 2, 150 XP\nNiveau 3 à 300 XP, encore 150 XP `███░░░░░░░`")
+}
+
+func TestProgressionCurve(t *testing.T) {
+	h := newHarness(t)
+	curve := &progression.Curve{Thresholds: []int{10, 20}, SkillPointsPerLevel: 2, BaseHP: 20, HPPerLevel: 5}
+	h.bot.progression = curve
+
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Dragon_600_1_1_1_-9")
+	expectContains(t, h.sayOne(10, "!hit"), "<@10> (+600 XP): Gain de niveau !  x2")
+
+	// the level stops at the end of the table
+	c := h.character(10)
+	if c.Level != 3 || c.SkillPoints != 9 || c.GetMaxHP(curve) != 20+1+15 {
+		t.Errorf("unexpected progression %+v", c)
+	}
+	expectContains(t, h.sayOne(10, "!xp"), "<@10> : niveau 3, 600 XP. Niveau maximum atteint !")
+}
+
+func TestFightsAreReproducible(t *testing.T) {
+	fight := func() []string {
+		h := newHarness(t)
+		h.sayOne(10, "!join_adventure")
+		h.sayOne(10, "!agi 5")
+		h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_20")
+
+		reports := []string{}
+		for i := 0; i < 5; i++ {
+			reports = append(reports, h.sayOne(10, "!hit"))
+		}
+		return reports
+	}
+
+	first, second := fight(), fight()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("hit %d differs: %q vs %q", i, first[i], second[i])
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(10, "!agi 5")
+
+	expectContains(t, h.sayOne(testGameMaster, "!replay"), "No fight to replay")
+	expectContains(t, h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_1"), "Monster spawned (#1)")
+
+	hits := 0
+	for ; hits < 20; hits++ {
+		if strings.Contains(h.sayOne(10, "!hit"), "vaincu") {
+			hits++
+			break
+		}
+	}
+
+	replay := h.sayOne(testGameMaster, "!replay")
+	expectContains(t, replay, "Fight #1 against Rat")
+	// a roll per hit, and one for every counter-attack
+	expectContains(t, replay, strconv.Itoa(2*hits-1)+" rolls:")
+	expectContains(t, replay, "#1 <@10> rolled ")
+	expectContains(t, replay, "#2 Rat rolled ")
+	if strings.Contains(replay, "MISMATCH") {
+		t.Errorf("replay does not match the fight: %q", replay)
+	}
+	if replay != h.sayOne(testGameMaster, "!replay 1") {
+		t.Error("replay by ID differs from the last fight replay")
+	}
+
+	expectContains(t, h.sayOne(10, "!replay"), "Vous n'êtes pas modérateur")
+}
+
+func TestCounterAttackAndKnockout(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Ogre_10_20_1_1_100")
+
+	report := h.sayOne(10, "!hit")
+	expectContains(t, report, "**Ogre** riposte et inflige ")
+	expectContains(t, report, "<@10> est K.O. !")
+
+	c := h.character(10)
+	if !c.IsKnockedOut() || c.CurrentHp != 0 {
+		t.Errorf("expected a knocked out character, got %+v", c)
+	}
+	expectContains(t, h.sayOne(10, "!character"), "0 / 12 HP - K.O.")
+
+	expectContains(t, h.sayOne(10, "!hit"), "Vous êtes K.O. !")
+	expectContains(t, h.sayOne(10, "!rest"), "Impossible de se reposer en plein combat !")
+
+	expectContains(t, h.sayOne(testGameMaster, "!revive <@10>"), "<@10> is revived (12 / 12 HP)!")
+	expectContains(t, h.sayOne(10, "!hit"), "inflige ")
+}
+
+func TestCounterAttackTargetsStandingFighters(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(11, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Ogre_10_20_1_1_100")
+
+	h.sayOne(10, "!hit")
+	h.sayOne(11, "!hit")
+
+	// every counter-attack knocks out a fighter: both are down after two of them
+	if !h.character(10).IsKnockedOut() || !h.character(11).IsKnockedOut() {
+		t.Errorf("expected both fighters to be knocked out: %+v, %+v", h.character(10), h.character(11))
+	}
+}
+
+func TestRestAfterFight(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Ogre_10_20_1_1_100")
+	h.sayOne(10, "!hit")
+
+	// the ogre flees
+	m, err := h.store.FetchMonsterInfo(h.campaign())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.CurrentHp = 0
+	if err := h.store.UpdateMonsterHP(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	expectContains(t, h.sayOne(10, "!rest"), "<@10> se repose et récupère ses forces (12 / 12 HP).")
+	if h.character(10).IsKnockedOut() {
+		t.Error("expected the character to be rested")
+	}
+}
+
+func TestStaminaCostAndRegeneration(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Dummy_0_0_0_1_1000")
+
+	for i := 0; i < db.MaxStamina/hitStaminaCost; i++ {
+		expectContains(t, h.sayOne(10, "!hit"), "inflige ")
+	}
+	expectContains(t, h.sayOne(10, "!hit"), "Vous êtes épuisé !")
+	expectContains(t, h.sayOne(10, "!character"), "Endurance : 0 / 100")
+
+	// 2 stamina per minute
+	h.advance(4 * time.Minute)
+	expectContains(t, h.sayOne(10, "!character"), "Endurance : 8 / 100")
+	expectContains(t, h.sayOne(10, "!hit"), "Vous êtes épuisé !")
+
+	h.advance(90 * time.Second)
+	expectContains(t, h.sayOne(10, "!hit"), "inflige ")
+	if c := h.character(10); c.Stamina != 0 {
+		t.Errorf("expected 0 stamina left, got %d", c.Stamina)
+	}
+}
+
+func TestEncounter(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(11, "!join_adventure")
+
+	expectContains(t, h.sayOne(testGameMaster, "!spawn Boss_100_0_0_1_90; Minion_20_0_0_1_-9"),
+		"2 monsters spawned (#1, #2)")
+
+	watch := h.sayOne(10, "!watch")
+	expectContains(t, watch, "1. Boss - 100 / 100 HP `██████████`")
+	expectContains(t, watch, "2. Minion - 1 / 1 HP `██████████`")
+
+	expectContains(t, h.sayOne(10, "!hit 3"), "Cible inconnue")
+	expectContains(t, h.sayOne(10, "!hit dragon"), "Cible inconnue")
+
+	// only the killer of the minion shares its experience
+	report := h.sayOne(11, "!hit min")
+	expectContains(t, report, "points de dégâts à **Minion**")
+	expectContains(t, report, "Le combat rapporte 20 points d'expérience partagés entre :\n- <@11> (+20 XP)\n")
+
+	report = h.sayOne(10, "!hit")
+	expectContains(t, report, "points de dégâts à **Boss**")
+	expectContains(t, report, "**Boss** riposte")
+
+	watch = h.sayOne(10, "!watch")
+	expectContains(t, watch, "Boss - ")
+	if strings.Contains(watch, "Minion") {
+		t.Errorf("the minion is dead: %q", watch)
+	}
+
+	if c := h.character(11); c.Experience != 20 {
+		t.Errorf("expected 20 XP, got %d", c.Experience)
+	}
+	if c := h.character(10); c.Experience != 0 {
+		t.Errorf("expected no XP, got %d", c.Experience)
+	}
+}
+
+func TestEncounterSize(t *testing.T) {
+	h := newHarness(t)
+
+	encounter := strings.TrimSuffix(strings.Repeat("Rat_1_1_1_1_1; ", maxSpawnCount+1), "; ")
+	expectContains(t, h.sayOne(testGameMaster, "!spawn "+encounter), "Too many monsters: 10 at most per !spawn")
+	expectContains(t, h.sayOne(testGameMaster, "!watch"), "There are no more monsters")
+}
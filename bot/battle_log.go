@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// recentFights is the number of fights listed by !battle_log
+const recentFights = 5
+
+// maxLogEvents is the number of actions shown in the log of a fight, the latest ones
+const maxLogEvents = 30
+
+// damageTotal is the damage dealt by a fighter during a battle, actorID 0 being the monster
+type damageTotal struct {
+	actorID uint
+	damage  int
+}
+
+// damageTotals sums the damage dealt by each fighter of the battle, in the order they joined it
+func damageTotals(events []db.BattleEvent) []damageTotal {
+	totals := []damageTotal{}
+	index := map[uint]int{}
+	for i := range events {
+		e := &events[i]
+		if e.Damage == 0 {
+			continue
+		}
+
+		if _, ok := index[e.ActorID]; !ok {
+			index[e.ActorID] = len(totals)
+			totals = append(totals, damageTotal{actorID: e.ActorID})
+		}
+		totals[index[e.ActorID]].damage += e.Damage
+	}
+	return totals
+}
+
+// battleLog writes the fights of a campaign, the characters being known by their ID in the events
+type battleLog struct {
+	tr      i18n.Translator
+	players map[uint]uint
+}
+
+func (l *battleLog) fighter(m *db.Monster, characterID uint) string {
+	if characterID == 0 {
+		return "**" + m.Name + "**"
+	}
+	return util.DiscordIDToText(l.players[characterID])
+}
+
+func (l *battleLog) status(m *db.Monster) string {
+	if m.CurrentHp <= 0 {
+		return l.tr.T("battle_log.defeated")
+	}
+	return l.tr.T("battle_log.fighting", i18n.Vars{"HP": m.CurrentHp, "MaxHP": m.GetMaxHP()})
+}
+
+func (l *battleLog) totals(m *db.Monster, events []db.BattleEvent) string {
+	totals := []string{}
+	for _, total := range damageTotals(events) {
+		totals = append(totals, l.tr.T("battle_log.total", i18n.Vars{
+			"Fighter": l.fighter(m, total.actorID),
+			"Damage":  total.damage,
+		}))
+	}
+	return strings.Join(totals, ", ")
+}
+
+// summary writes a fight on a single line, with the damage totals
+func (l *battleLog) summary(m *db.Monster, events []db.BattleEvent) string {
+	return l.tr.T("battle_log.entry", i18n.Vars{
+		"ID":      m.ID,
+		"Monster": m.Name,
+		"Status":  l.status(m),
+		"Totals":  l.totals(m, events),
+	})
+}
+
+// details writes every action of a fight, then the damage totals
+func (l *battleLog) details(m *db.Monster, events []db.BattleEvent) string {
+	str := l.tr.T("battle_log.title", i18n.Vars{"ID": m.ID, "Monster": m.Name, "Status": l.status(m)}) + "\n"
+	if len(events) == 0 {
+		return str + l.tr.T("battle_log.empty") + "\n"
+	}
+
+	first := 0
+	if len(events) > maxLogEvents {
+		first = len(events) - maxLogEvents
+		str += l.tr.N("battle_log.earlier", first) + "\n"
+	}
+	for i := first; i < len(events); i++ {
+		e := &events[i]
+		str += strconv.Itoa(i+1) + ". " + l.tr.T("battle_log."+e.Action, i18n.Vars{
+			"Actor":  l.fighter(m, e.ActorID),
+			"Target": l.fighter(m, e.TargetID),
+			"Roll":   e.Roll,
+			"Damage": e.Damage,
+			"Heal":   e.Heal,
+			"HP":     e.HP,
+		}) + "\n"
+	}
+
+	return str + l.tr.T("battle_log.totals", i18n.Vars{"Totals": l.totals(m, events)}) + "\n"
+}
+
+// battleLogCmd lists the last fights of the campaign, or details one of them
+func (b *Bot) battleLogCmd(req *Request) _Response {
+	characters, err := b.db.FetchCharacters(req.Campaign.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch characters: %w", err), req.T("battle_log.error"))
+	}
+	l := battleLog{tr: req.tr, players: map[uint]uint{}}
+	for i := range characters {
+		l.players[characters[i].ID] = characters[i].UserID
+	}
+
+	if req.args.Has("monster id") {
+		monster, err := b.db.FetchMonster(req.Campaign.ID, uint(req.args.Int("monster id")))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return simpleErr(err, req.T("battle_log.unknown"))
+		}
+		if err != nil {
+			return simpleErr(fmt.Errorf("cannot fetch monster: %w", err), req.T("battle_log.error"))
+		}
+
+		events, err := b.db.FetchEvents(monster.ID)
+		if err != nil {
+			return simpleErr(fmt.Errorf("cannot fetch battle log: %w", err), req.T("battle_log.error"))
+		}
+		return simpleResponse(l.details(&monster, events))
+	}
+
+	monsters, err := b.db.FetchFoughtMonsters(req.Campaign.ID, recentFights)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch fights: %w", err), req.T("battle_log.error"))
+	}
+	if len(monsters) == 0 {
+		return simpleResponse(req.T("battle_log.none"))
+	}
+
+	str := req.T("battle_log.recent") + "\n"
+	for i := range monsters {
+		events, err := b.db.FetchEvents(monsters[i].ID)
+		if err != nil {
+			return simpleErr(fmt.Errorf("cannot fetch battle log: %w", err), req.T("battle_log.error"))
+		}
+		str += l.summary(&monsters[i], events) + "\n"
+	}
+	return simpleResponse(str + req.T("battle_log.more", i18n.Vars{"Prefix": req.Campaign.CommandPrefix()}))
+}
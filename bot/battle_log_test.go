@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+)
+
+func TestBattleLog(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(11, "!join_adventure soigneur")
+
+	expectContains(t, h.sayOne(10, "!battle_log"), "Aucun combat pour l'instant.")
+
+	h.sayOne(testGameMaster, "!spawn Boss_10_0_0_0_90")
+	h.sayOne(10, "!hit")
+	h.sayOne(11, "!hit")
+	h.sayOne(11, "!heal <@10>")
+
+	events, err := h.store.FetchEvents(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 2 hits, 2 counter-attacks and a heal, got %+v", events)
+	}
+
+	log := h.sayOne(10, "!battle_log 1")
+	expectContains(t, log, "Combat n°1 contre Boss, ")
+	expectContains(t, log, "1. <@10> frappe **Boss** : jet "+strconv.Itoa(events[0].Roll)+
+		", "+strconv.Itoa(events[0].Damage)+" dégâts ("+strconv.Itoa(events[0].HP)+" HP)\n")
+	expectContains(t, log, "2. **Boss** riposte contre <@")
+	expectContains(t, log, "3. <@11> frappe **Boss** de son bâton : ")
+	expectContains(t, log, "5. <@11> soigne <@10> de ")
+	// the damage of every fighter, in the order they joined the fight
+	expectContains(t, log, "Dégâts infligés : <@10> "+strconv.Itoa(events[0].Damage)+
+		", **Boss** 2, <@11> "+strconv.Itoa(events[2].Damage)+"\n")
+
+	expectContains(t, h.sayOne(testGameMaster, "!battle_log 1"), "Fight #1 against Boss, ")
+	expectContains(t, h.sayOne(10, "!battle_log 9"), "Combat inconnu")
+
+	h.sayOne(testGameMaster, "!spawn Rat_10_0_0_0_-9")
+	h.sayOne(10, "!hit rat")
+
+	recent := h.sayOne(10, "!battle_log")
+	expectContains(t, recent, "Derniers combats :\n#2 Rat, vaincu - <@10> 1\n#1 Boss, ")
+	expectContains(t, recent, "Détaillez un combat avec `!battle_log <id>`")
+}
+
+func TestHealIsLoggedInTheBattleOfTheTarget(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(11, "!join_adventure soigneur")
+	h.sayOne(12, "!join_adventure")
+
+	h.sayOne(testGameMaster, "!spawn Rat_10_0_0_0_90; Boss_10_0_0_0_90")
+	h.sayOne(10, "!hit boss")
+	h.sayOne(11, "!heal <@10>")
+	h.sayOne(11, "!heal <@12>")
+
+	if events, err := h.store.FetchEvents(1); err != nil || len(events) != 0 {
+		t.Errorf("expected nothing against the rat, got %+v (%v)", events, err)
+	}
+	events, err := h.store.FetchEvents(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[2].Action != db.ActionHeal {
+		t.Errorf("expected a hit, a counter-attack and the heal of the fighter, got %+v", events)
+	}
+}
+
+func TestBattleLogShowsTheLatestActions(t *testing.T) {
+	tr := newHarness(t).bot.messages.Translator()
+	m := db.Monster{Name: "Rat", CurrentHp: 0}
+	events := []db.BattleEvent{}
+	for i := 0; i < maxLogEvents+2; i++ {
+		events = append(events, db.BattleEvent{Action: db.ActionStrike, ActorID: 1, Damage: 1})
+	}
+
+	l := battleLog{tr: tr, players: map[uint]uint{1: 10}}
+	details := l.details(&m, events)
+	expectContains(t, details, "Combat n°0 contre Rat, vaincu :\n… 2 actions plus tôt\n3. <@10> frappe **Rat**")
+	expectContains(t, details, "Dégâts infligés : <@10> "+strconv.Itoa(maxLogEvents+2))
+	if strings.Contains(details, "\n2. ") {
+		t.Errorf("expected the earlier actions to be hidden: %q", details)
+	}
+}
@@ -0,0 +1,219 @@
+package bestiary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+)
+
+const templateExt = ".json"
+
+var (
+	// ErrUnknownTemplate is returned when no template matches a key
+	ErrUnknownTemplate = errors.New("unknown monster template")
+	// ErrInvalidTemplate is returned for a template a monster could not fight with
+	ErrInvalidTemplate = errors.New("invalid monster template")
+)
+
+// Loot is an entry of a loot table: Quantity items dropped with Chance (0 to 1)
+type Loot struct {
+	Item     string
+	Chance   float64
+	Quantity int
+}
+
+// Template describes a kind of monster
+type Template struct {
+	// Key is the file name without extension, used in !spawn
+	Key          string `json:"-"`
+	Name         string
+	Description  string
+	Experience   int
+	Gold         int
+	Strength     int
+	Agility      int
+	Wisdom       int
+	Constitution int
+	Loot         []Loot
+	Abilities    []string
+	// Image is the URL of a picture of the monster, shown on Discord
+	Image string
+}
+
+// Bestiary holds the monster templates of a directory, one JSON file per template.
+// The directory is reloaded when its files change. A broken file keeps the last good version of its template.
+type Bestiary struct {
+	dir string
+
+	mu        sync.Mutex
+	templates map[string]Template
+	// fingerprint of the directory files when it was loaded
+	fingerprint string
+}
+
+// New returns the bestiary of dir. A missing directory is an empty bestiary.
+func New(dir string) (*Bestiary, error) {
+	b := &Bestiary{dir: dir}
+	if err := b.refresh(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Get returns the template of key, case insensitive
+func (b *Bestiary) Get(key string) (Template, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.refresh(); err != nil {
+		return Template{}, err
+	}
+
+	t, ok := b.templates[strings.ToLower(key)]
+	if !ok {
+		return Template{}, fmt.Errorf("%q: %w", key, ErrUnknownTemplate)
+	}
+	return t, nil
+}
+
+// List returns every template, sorted by key
+func (b *Bestiary) List() ([]Template, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.refresh(); err != nil {
+		return nil, err
+	}
+
+	templates := make([]Template, 0, len(b.templates))
+	for key := range b.templates {
+		templates = append(templates, b.templates[key])
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Key < templates[j].Key })
+	return templates, nil
+}
+
+// refresh reloads the templates if a file was added, removed or modified since the last load.
+// Once loaded, the templates are kept when the directory cannot be read.
+func (b *Bestiary) refresh() error {
+	fingerprint, err := b.fingerprintFiles()
+	if err != nil && b.templates != nil {
+		log.Warn().Err(err).Str("dir", b.dir).Msg("cannot read the bestiary, keeping the loaded templates")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if b.templates != nil && fingerprint == b.fingerprint {
+		return nil
+	}
+
+	if err := b.load(); err != nil {
+		return err
+	}
+	b.fingerprint = fingerprint
+	return nil
+}
+
+// fingerprintFiles summarizes the name, size and modification time of the directory files
+func (b *Bestiary) fingerprintFiles() (string, error) {
+	files, err := ioutil.ReadDir(b.dir)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fingerprint := ""
+	for _, f := range files {
+		fingerprint += fmt.Sprintf("%s:%d:%d;", f.Name(), f.Size(), f.ModTime().UnixNano())
+	}
+	return fingerprint, nil
+}
+
+func (b *Bestiary) load() error {
+	templates := map[string]Template{}
+
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != templateExt {
+			continue
+		}
+
+		path := filepath.Join(b.dir, f.Name())
+		t, err := loadTemplate(path)
+		if err != nil {
+			// a file being edited must not break the fights: keep its last good version, if any
+			key := templateKey(path)
+			log.Warn().Err(err).Str("template", key).Msg("skipping a broken monster template")
+			if previous, ok := b.templates[key]; ok {
+				templates[key] = previous
+			}
+			continue
+		}
+		templates[t.Key] = t
+	}
+
+	b.templates = templates
+	return nil
+}
+
+func loadTemplate(path string) (Template, error) {
+	t := Template{}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return t, err
+	}
+
+	if err := json.Unmarshal(content, &t); err != nil {
+		return t, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	t.Key = templateKey(path)
+	if t.Name == "" {
+		t.Name = t.Key
+	}
+	if err := t.validate(); err != nil {
+		return t, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+func templateKey(path string) string {
+	return strings.ToLower(strings.TrimSuffix(filepath.Base(path), templateExt))
+}
+
+// validate rejects the negative stats and rewards, the constitution leaving no HP, and the loot out of bounds
+func (t *Template) validate() error {
+	for _, stat := range []int{t.Experience, t.Gold, t.Strength, t.Agility, t.Wisdom} {
+		if stat < 0 {
+			return fmt.Errorf("negative stat or reward: %w", ErrInvalidTemplate)
+		}
+	}
+	if t.Constitution < db.MinMonsterConstitution {
+		return fmt.Errorf("constitution below %d: %w", db.MinMonsterConstitution, ErrInvalidTemplate)
+	}
+	for _, loot := range t.Loot {
+		if loot.Chance < 0 || loot.Chance > 1 || loot.Quantity < 0 {
+			return fmt.Errorf("loot %q: chance out of 0-1 or negative quantity: %w", loot.Item, ErrInvalidTemplate)
+		}
+	}
+	return nil
+}
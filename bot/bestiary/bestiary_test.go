@@ -0,0 +1,120 @@
+package bestiary
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTemplate(t *testing.T, dir string, key string, content string, modTime time.Time) {
+	t.Helper()
+
+	path := filepath.Join(dir, key+templateExt)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHotReload(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	writeTemplate(t, dir, "Rat", `{"Name": "Rat", "Experience": 10}`, start)
+
+	b, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rat, err := b.Get("rat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rat.Key != "rat" || rat.Experience != 10 {
+		t.Errorf("unexpected template %+v", rat)
+	}
+
+	if _, err := b.Get("wolf"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("expected an unknown template, got %v", err)
+	}
+
+	// modified and added files are loaded
+	writeTemplate(t, dir, "rat", `{"Name": "Rat", "Experience": 15}`, start.Add(time.Minute))
+	writeTemplate(t, dir, "wolf", `{"Experience": 30}`, start.Add(time.Minute))
+
+	templates, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 2 || templates[0].Experience != 15 || templates[1].Name != "wolf" {
+		t.Errorf("unexpected templates %+v", templates)
+	}
+
+	// a broken file keeps its last good version, and the other templates
+	writeTemplate(t, dir, "wolf", `{"Experience": `, start.Add(2*time.Minute))
+	writeTemplate(t, dir, "bat", `{"Experience": `, start.Add(2*time.Minute))
+	if wolf, err := b.Get("wolf"); err != nil || wolf.Experience != 30 {
+		t.Errorf("expected the last good wolf, got %+v, %v", wolf, err)
+	}
+	if _, err := b.Get("bat"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("expected the broken bat to be skipped, got %v", err)
+	}
+	if _, err := b.Get("rat"); err != nil {
+		t.Error(err)
+	}
+
+	writeTemplate(t, dir, "wolf", `{"Experience": 40}`, start.Add(3*time.Minute))
+	if wolf, err := b.Get("wolf"); err != nil || wolf.Experience != 40 {
+		t.Errorf("expected the fixed wolf, got %+v, %v", wolf, err)
+	}
+}
+
+func TestInvalidTemplates(t *testing.T) {
+	for _, content := range []string{
+		`{"Agility": -1}`,
+		`{"Gold": -5}`,
+		`{"Constitution": -10}`,
+		`{"Loot": [{"Item": "potion", "Chance": 1.5}]}`,
+		`{"Loot": [{"Item": "potion", "Chance": 0.5, "Quantity": -1}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "rat"+templateExt)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadTemplate(path); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("%s: expected an invalid template, got %v", content, err)
+		}
+	}
+}
+
+func TestMissingDirectory(t *testing.T) {
+	b, err := New(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := b.List()
+	if err != nil || len(templates) != 0 {
+		t.Errorf("expected an empty bestiary, got %v, %v", templates, err)
+	}
+}
+
+func TestShippedBestiary(t *testing.T) {
+	b, err := New(filepath.Join("..", "..", "bestiary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Error("expected templates in the shipped bestiary")
+	}
+}
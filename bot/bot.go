@@ -1,15 +1,20 @@
 package bot
 
 import (
-	"strconv"
-	"strings"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
 
+	"github.com/vincent-heng/discord-airpgbot/bot/bestiary"
 	"github.com/vincent-heng/discord-airpgbot/bot/db"
-	"github.com/vincent-heng/discord-airpgbot/bot/util"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/items"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
 	"github.com/vincent-heng/discord-airpgbot/config"
 )
 
@@ -17,12 +22,31 @@ import (
 type Bot struct {
 	config.Config
 
-	db *db.DB
+	db          db.Store
+	bestiary    *bestiary.Bestiary
+	items       *items.Catalog
+	messages    *i18n.Catalog
+	progression *progression.Curve
+	dice        *dice
+	now         func() time.Time
+
+	campaigns  campaignCache
+	adventures adventureCache
 }
 
+const (
+	defaultBestiaryDir = "bestiary"
+	defaultItemsFile   = "items.json"
+	defaultProgression = "progression.json"
+	defaultLocalesDir  = "locales"
+	defaultLocale      = "fr"
+)
+
 type _Message struct {
 	Channel string
+	// Message is the plain text, for the transports without embeds
 	Message string
+	Embed   *discordgo.MessageEmbed
 }
 
 func (m _Message) getChan(id string) string {
@@ -50,6 +74,22 @@ func simpleErr(err error, msg string) _Response {
 	}
 }
 
+// deliver sends a message through the transport, as an embed or privately when it can
+func deliver(t Transport, channelID string, msg *_Message, private bool) error {
+	if e, ok := t.(embedTransport); ok && msg.Embed != nil {
+		return e.SendEmbed(channelID, msg.Embed, private)
+	}
+	if p, ok := t.(privateTransport); ok && private {
+		return p.SendPrivate(channelID, msg.Message)
+	}
+	return t.Send(channelID, msg.Message)
+}
+
+// badSyntax lets the dispatcher answer with the usage of the command
+func badSyntax(err error) _Response {
+	return _Response{err: fmt.Errorf("bad syntax: %w", err)}
+}
+
 func simpleResponse(msg string) _Response {
 	return _Response{
 		msgs: []_Message{
@@ -58,106 +98,168 @@ func simpleResponse(msg string) _Response {
 	}
 }
 
-type _Handler func(*Bot, *discordgo.Session, *discordgo.MessageCreate, uint) _Response
+// messageResponse answers with a rendered message, see render.go
+func messageResponse(msg _Message) _Response {
+	return _Response{msgs: []_Message{msg}}
+}
+
+type _Handler func(*Bot, *Request) _Response
+
+// campaignTranslator writes the messages sent to the whole campaign, like the announces of the adventure channel
+func (b *Bot) campaignTranslator(c *db.Campaign) i18n.Translator {
+	return b.messages.Translator(c.Locale)
+}
 
 // New instantiates a bot with config
 func New(conf config.Config) (*Bot, error) {
-	database, err := db.New()
+	database, err := db.New(conf)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Bot{
-		Config: conf,
-		db:     database,
-	}, nil
-}
+	if conf.BestiaryDir == "" {
+		conf.BestiaryDir = defaultBestiaryDir
+	}
+	monsters, err := bestiary.New(conf.BestiaryDir)
+	if err != nil {
+		return nil, err
+	}
 
-var (
-	// cmd router
-	router = map[string]_Handler{ //nolint:gochecknoglobals
-		"characters":     (*Bot).charactersCmd,
-		"join_adventure": (*Bot).joinAdventure,
-		"character":      (*Bot).characterCmd,
-		"watch":          (*Bot).watchCmd,
-		"hit":            (*Bot).hitCmd,
-		"str":            handleUpStatsFunctor("strength"),
-		"agi":            handleUpStatsFunctor("agility"),
-		"wis":            handleUpStatsFunctor("wisdom"),
-		"con":            handleUpStatsFunctor("constitution"),
-		// game master cmd
-		"start_adventure": gameMasterCmdFunctor((*Bot).startAdventureCmd),
-		"shout":           gameMasterCmdFunctor((*Bot).shoutCmd),
-		"spawn":           gameMasterCmdFunctor((*Bot).spawnCmd),
+	if conf.ItemsFile == "" {
+		conf.ItemsFile = defaultItemsFile
+	}
+	catalog, err := items.Load(conf.ItemsFile)
+	if err != nil {
+		return nil, err
 	}
-)
 
-func gameMasterCmdFunctor(handler _Handler) _Handler {
-	return func(b *Bot, s *discordgo.Session, m *discordgo.MessageCreate, authorID uint) _Response {
-		// GM commands
-		if authorID != b.Config.GameMaster {
-			return simpleErr(errNotGameMaster, "")
-		}
-		return handler(b, s, m, authorID)
+	if conf.ProgressionFile == "" {
+		conf.ProgressionFile = defaultProgression
+	}
+	curve, err := progression.Load(conf.ProgressionFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.LocalesDir == "" {
+		conf.LocalesDir = defaultLocalesDir
 	}
+	if conf.Locale == "" {
+		conf.Locale = defaultLocale
+	}
+	messages, err := i18n.Load(conf.LocalesDir, conf.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bot{
+		Config:      conf,
+		db:          database,
+		bestiary:    monsters,
+		items:       catalog,
+		messages:    messages,
+		progression: curve,
+		dice:        newDice(time.Now().UnixNano()),
+		now:         time.Now,
+	}, nil
 }
 
 func handleUpStatsFunctor(stat string) _Handler {
-	return func(b *Bot, s *discordgo.Session, m *discordgo.MessageCreate, userID uint) _Response {
-		return b.handleUpStats(s, m, userID, stat)
+	return func(b *Bot, req *Request) _Response {
+		return b.handleUpStats(req, stat)
 	}
 }
 
-// Handler for discord events
-func (b *Bot) Handler(s *discordgo.Session, m *discordgo.MessageCreate) {
-	// Ignore all messages created by the bot itself
-	if m.Author.ID == s.State.User.ID {
+// Dispatch routes a request to its command handler and sends the response through the transport
+func (b *Bot) Dispatch(t Transport, req *Request) {
+	campaign, err := b.findCampaign(req)
+	notFound := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !notFound {
+		log.Error().Err(err).Msg("[Response]")
 		return
 	}
 
-	content := strings.Split(m.Content, " ")
-	if len(content) < 1 {
+	// slash commands come parsed, messages without campaign use the default prefix
+	if req.Options == nil && !req.parse(campaign.CommandPrefix()) {
+		return
+	}
+	cmd, ok := router[req.Command]
+	if !ok {
+		// not a cmd
 		return
 	}
 
-	authorID64, err := strconv.ParseUint(strings.TrimSpace(m.Author.ID), 10, 64)
-	if err != nil {
-		log.Warn().Msg("[Response] Unexpected error (authorID not an integer)")
-		s.ChannelMessageSend(m.ChannelID, "Erreur inattendue :cry:")
+	if notFound && req.GuildID != "" {
+		campaign, err = b.createCampaign(req)
+		if err != nil {
+			log.Error().Err(err).Msg("[Response]")
+			return
+		}
+		notFound = false
 	}
-	authorID := uint(authorID64)
 
-	channelID, err := util.GetChannelID()
+	player, err := b.db.FetchPlayer(req.AuthorID)
 	if err != nil {
 		log.Error().Err(err).Msg("[Response]")
 		return
 	}
+	req.Player = &player
+	req.tr = b.messages.Translator(player.Locale, campaign.Locale)
 
-	handler, ok := router[content[0][1:]]
-	if !ok {
-		// not a cmd
+	if notFound {
+		if e := t.Send(req.ChannelID, req.T("campaign.not_found")); e != nil {
+			log.Error().Err(e).Msg("cannot push message")
+		}
+		return
+	}
+	req.Campaign = &campaign
+
+	adventure, err := b.adventure(campaign.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("[Response]")
 		return
 	}
 
-	if channelID != m.ChannelID && authorID != b.Config.GameMaster {
-		log.Warn().Str("expected", channelID).Str("current", m.ChannelID).Msg("request on a wrong channel")
-		// return
+	role, err := b.role(req)
+	if err != nil {
+		log.Error().Err(err).Msg("[Response]")
+		return
 	}
 
 	uuid := uuid.New().String()
 	log.Debug().
-		Str("cmd", content[0]).
-		Uint("user", authorID).
-		Strs("params", content[1:]).
+		Str("cmd", req.Command).
+		Uint("user", req.AuthorID).
+		Strs("params", req.Args).
 		Str("cmdID", uuid).
 		Msg("calling handler for cmd")
 
-	resp := handler(b, s, m, authorID)
+	var resp _Response
+	if redirect := checkChannel(cmd, req, &adventure, role); redirect != nil {
+		log.Debug().Str("expected", adventure.ChannelID).Str("current", req.ChannelID).Msg("request on a wrong channel")
+		resp = *redirect
+	} else if denied := authorize(req, cmd, role); denied != nil {
+		resp = *denied
+	} else if args, err := req.readArgs(cmd); err != nil {
+		resp = cmd.usageErr(req, err)
+	} else {
+		req.args = args
+		resp = cmd.handler(b, req)
+	}
+
+	// the handlers leave the usage to the registry
+	if resp.err != nil && len(resp.msgs) == 0 && errors.Is(resp.err, errIllegalArgument) {
+		resp = cmd.usageErr(req, resp.err)
+	}
+
+	// errors and personal answers are hidden from the other players, when the transport can
+	private := cmd.Ephemeral || resp.err != nil
 
 	for i := range resp.msgs {
 		msg := &resp.msgs[i]
+		channelID := msg.getChan(req.ChannelID)
 
-		if _, err := s.ChannelMessageSend(msg.getChan(m.ChannelID), msg.Message); err != nil {
+		if err := deliver(t, channelID, msg, private && channelID == req.ChannelID); err != nil {
 			log.Error().Err(err).Msg("cannot push message")
 		}
 	}
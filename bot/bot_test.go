@@ -0,0 +1,293 @@
+package bot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/bestiary"
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/items"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+	"github.com/vincent-heng/discord-airpgbot/config"
+)
+
+const (
+	testGameMaster = 1
+	testBot        = "500"
+	testGuild      = "guild"
+	testChannel    = "adventure"
+	testSeed       = 42
+	testItems      = `{
+  "potion": {"Name": "Potion", "Kind": "consumable", "Heal": 5, "Stamina": 20},
+  "sword": {"Name": "Sword", "Kind": "weapon", "Attack": 3},
+  "club": {"Name": "Club", "Kind": "weapon", "Attack": 1},
+  "shield": {"Name": "Shield", "Kind": "armor", "Defense": 100}
+}`
+)
+
+type sentMessage struct {
+	Channel string
+	Message string
+}
+
+// fakeSession is a Transport recording every message sent by the bot
+type fakeSession struct {
+	sent []sentMessage
+}
+
+func (f *fakeSession) Send(channelID string, message string) error {
+	f.sent = append(f.sent, sentMessage{Channel: channelID, Message: message})
+	return nil
+}
+
+// harness runs a bot on an in-memory store with seeded dice and a fake clock
+type harness struct {
+	t       *testing.T
+	bot     *Bot
+	store   *db.DB
+	session *fakeSession
+	clock   time.Time
+	// roles are the Discord roles of the players
+	roles map[uint][]string
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	// the bestiary and the item catalog are read from the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the message catalog is the one of the repository, next to the sources
+	_, source, _, _ := runtime.Caller(0)
+	messages, err := i18n.Load(filepath.Join(filepath.Dir(source), "..", defaultLocalesDir), defaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Error(err)
+		}
+	})
+
+	store, err := db.NewSQLite("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.DB = store.DB.Session(&gorm.Session{Logger: logger.Discard})
+	t.Cleanup(func() {
+		if sqlDB, err := store.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	h := &harness{
+		t:       t,
+		store:   store,
+		session: &fakeSession{},
+		clock:   time.Date(2020, 12, 1, 20, 0, 0, 0, time.UTC),
+	}
+	// the bestiary directory is created by addTemplate
+	monsters, err := bestiary.New(defaultBestiaryDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(defaultItemsFile, []byte(testItems), 0600); err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := items.Load(defaultItemsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.bot = &Bot{
+		Config:      config.Config{GameMaster: testGameMaster},
+		db:          store,
+		bestiary:    monsters,
+		items:       catalog,
+		messages:    messages,
+		progression: progression.Default(),
+		dice:        newDice(testSeed),
+		now:         func() time.Time { return h.clock },
+	}
+
+	// the game master plays in English, the players in French
+	if err := store.SavePlayer(&db.Player{UserID: testGameMaster, Locale: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// addTemplate writes a monster template in the bestiary
+func (h *harness) addTemplate(key string, template string) {
+	h.t.Helper()
+
+	if err := os.MkdirAll(defaultBestiaryDir, 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(defaultBestiaryDir, key+".json"), []byte(template), 0600); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// advance moves the fake clock forward
+func (h *harness) advance(d time.Duration) {
+	h.clock = h.clock.Add(d)
+}
+
+// say sends content as authorID on the adventure channel and returns the bot answers
+func (h *harness) say(authorID uint, content string) []string {
+	h.t.Helper()
+
+	return h.sayIn(testGuild, authorID, content)
+}
+
+// sayIn sends content as authorID on the adventure channel of a guild, empty for a direct message
+func (h *harness) sayIn(guildID string, authorID uint, content string) []string {
+	h.t.Helper()
+
+	return h.sayOn(guildID, testChannel, authorID, content)
+}
+
+// sayOn sends content as authorID on a channel of a guild
+func (h *harness) sayOn(guildID string, channelID string, authorID uint, content string) []string {
+	h.t.Helper()
+
+	req := NewRequest(content, authorID, "player", channelID)
+	req.GuildID = guildID
+	req.BotID = testBot
+	req.RoleIDs = h.roles[authorID]
+
+	before := len(h.session.sent)
+	h.bot.Dispatch(h.session, req)
+
+	answers := []string{}
+	for _, msg := range h.session.sent[before:] {
+		answers = append(answers, msg.Message)
+	}
+	return answers
+}
+
+// sayOne sends content and expects a single answer
+func (h *harness) sayOne(authorID uint, content string) string {
+	h.t.Helper()
+
+	answers := h.say(authorID, content)
+	if len(answers) != 1 {
+		h.t.Fatalf("%q: expected 1 answer, got %q", content, answers)
+	}
+	return answers[0]
+}
+
+// campaign returns the ID of the test guild campaign
+func (h *harness) campaign() uint {
+	h.t.Helper()
+
+	c, err := h.store.FetchOrCreateCampaign(testGuild, testGameMaster)
+	if err != nil {
+		h.t.Fatalf("fetch campaign: %v", err)
+	}
+	return c.ID
+}
+
+func (h *harness) character(id uint) db.Character {
+	h.t.Helper()
+
+	c, err := h.store.FetchCharacterInfo(h.campaign(), id)
+	if err != nil {
+		h.t.Fatalf("fetch character %d: %v", id, err)
+	}
+	return c
+}
+
+func expectContains(t *testing.T, got string, want string) {
+	t.Helper()
+
+	if !strings.Contains(got, want) {
+		t.Errorf("expected %q to contain %q", got, want)
+	}
+}
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		content string
+		command string
+		text    string
+	}{
+		{"!shout Hello  world ", "shout", "Hello  world"},
+		{"  !stock\tsword   10 ", "stock", "sword   10"},
+		{"<@500> hit goblin", "hit", "goblin"},
+		{"<@!500>  !hit", "hit", ""},
+		{"rpg!hit", "", ""},
+		{"! hit", "", ""},
+		{"?hit", "", ""},
+		{"<@501> hit", "", ""},
+		{"<@500>", "", ""},
+		{"", "", ""},
+	}
+	for _, c := range cases {
+		req := NewRequest(c.content, 7, "bob", "chan")
+		req.BotID = testBot
+
+		ok := req.parse("!")
+		if ok != (c.command != "") || req.Command != c.command || req.Text != c.text {
+			t.Errorf("%q: unexpected command %q (%q), parsed %v", c.content, req.Command, req.Text, ok)
+		}
+	}
+
+	req := NewRequest("rpg!stock sword 10", 7, "bob", "chan")
+	if !req.parse("rpg!") || req.Command != "stock" || len(req.Args) != 2 {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestUnknownCommandIsIgnored(t *testing.T) {
+	h := newHarness(t)
+
+	if answers := h.say(10, "!dance"); len(answers) != 0 {
+		t.Errorf("expected no answer, got %q", answers)
+	}
+}
+
+func TestGameMasterCommandsAreRestricted(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(10, "!spawn Rat_10_1_1_1_1"), "Vous n'êtes pas maître du jeu")
+	expectContains(t, h.sayOne(10, "!watch"), "Il n'y a plus de monstre")
+
+	expectContains(t, h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_1"), "Monster spawned")
+	expectContains(t, h.sayOne(10, "!watch"), "Rat - 11 / 11 HP")
+}
+
+func TestShoutToAdventureChannel(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(testGameMaster, "!shout Hello"), "!start_adventure")
+	h.sayOne(testGameMaster, "!start_adventure")
+
+	h.sayOne(testGameMaster, "!shout Hello adventurers")
+	last := h.session.sent[len(h.session.sent)-1]
+	if last.Channel != testChannel || last.Message != "Hello adventurers" {
+		t.Errorf("unexpected shout %+v", last)
+	}
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+)
+
+const maxPrefixLength = 5
+
+// campaignCache keeps the campaign of every guild, read on each message to find the command prefix
+type campaignCache struct {
+	mu        sync.Mutex
+	campaigns map[string]db.Campaign
+}
+
+// findCampaign finds the campaign of the guild, gorm.ErrRecordNotFound until its first command.
+// Direct messages play in the last campaign of the author.
+func (b *Bot) findCampaign(req *Request) (db.Campaign, error) {
+	if req.GuildID == "" {
+		return b.db.FetchLatestCampaign(req.AuthorID)
+	}
+
+	b.campaigns.mu.Lock()
+	defer b.campaigns.mu.Unlock()
+
+	if c, ok := b.campaigns.campaigns[req.GuildID]; ok {
+		return c, nil
+	}
+
+	c, err := b.db.FetchCampaign(req.GuildID)
+	if err != nil {
+		return c, err
+	}
+
+	if b.campaigns.campaigns == nil {
+		b.campaigns.campaigns = map[string]db.Campaign{}
+	}
+	b.campaigns.campaigns[req.GuildID] = c
+	return c, nil
+}
+
+// createCampaign creates the campaign of the guild, on its first command
+func (b *Bot) createCampaign(req *Request) (db.Campaign, error) {
+	b.campaigns.mu.Lock()
+	defer b.campaigns.mu.Unlock()
+
+	c, err := b.db.FetchOrCreateCampaign(req.GuildID, b.Config.GameMaster)
+	if err != nil {
+		return c, err
+	}
+
+	if b.campaigns.campaigns == nil {
+		b.campaigns.campaigns = map[string]db.Campaign{}
+	}
+	b.campaigns.campaigns[req.GuildID] = c
+	return c, nil
+}
+
+// saveCampaign persists the campaign, then updates the cache
+func (b *Bot) saveCampaign(c *db.Campaign) error {
+	b.campaigns.mu.Lock()
+	defer b.campaigns.mu.Unlock()
+
+	if err := b.db.SaveCampaign(c); err != nil {
+		return err
+	}
+
+	if b.campaigns.campaigns == nil {
+		b.campaigns.campaigns = map[string]db.Campaign{}
+	}
+	b.campaigns.campaigns[c.GuildID] = *c
+	return nil
+}
+
+// prefixCmd shows or changes the prefix of the commands, mentioning the bot always works
+func (b *Bot) prefixCmd(req *Request) _Response {
+	if !req.args.Has("prefix") {
+		return simpleResponse(req.T("prefix.current", i18n.Vars{"Prefix": req.Campaign.CommandPrefix()}))
+	}
+
+	prefix := req.args.String("prefix")
+	if !validPrefix(prefix) {
+		return simpleErr(fmt.Errorf("prefix %q: %w", prefix, errIllegalArgument),
+			req.T("prefix.invalid", i18n.Vars{"Max": maxPrefixLength}))
+	}
+
+	req.Campaign.Prefix = prefix
+	if err := b.saveCampaign(req.Campaign); err != nil {
+		return simpleErr(fmt.Errorf("cannot save campaign: %w", err), req.T("prefix.error"))
+	}
+
+	return simpleResponse(req.T("prefix.done", i18n.Vars{"Prefix": prefix}))
+}
+
+// splitCmd shows or changes how the rewards of the fights are shared
+func (b *Bot) splitCmd(req *Request) _Response {
+	if !req.args.Has("strategy") {
+		return simpleResponse(req.T("split.current", i18n.Vars{"Name": splitName(req.tr, req.Campaign.Split())}))
+	}
+
+	req.Campaign.RewardSplit = req.args.String("strategy")
+	if err := b.saveCampaign(req.Campaign); err != nil {
+		return simpleErr(fmt.Errorf("cannot save campaign: %w", err), req.T("split.error"))
+	}
+
+	return simpleResponse(req.T("split.done", i18n.Vars{"Name": splitName(req.tr, req.Campaign.Split())}))
+}
+
+// splitName translates a reward split strategy
+func splitName(tr i18n.Translator, strategy string) string {
+	return tr.T("split.name."+strategy, i18n.Vars{"Bonus": lastHitBonus})
+}
+
+// langCmd shows or changes the language of the author, or of the whole campaign for the game masters
+func (b *Bot) langCmd(req *Request) _Response {
+	if !req.args.Has("locale") {
+		locales := []string{}
+		for _, locale := range b.messages.Locales() {
+			locales = append(locales, req.T("lang.entry", i18n.Vars{
+				"Locale": locale, "Name": b.messages.Translator(locale).T("locale.name"),
+			}))
+		}
+		return simpleResponse(req.T("lang.current", i18n.Vars{
+			"Name": req.T("locale.name"), "Locales": strings.Join(locales, ", "),
+		}))
+	}
+
+	locale := strings.ToLower(req.args.String("locale"))
+	if !b.messages.Has(locale) {
+		return simpleErr(fmt.Errorf("locale %q: %w", locale, errIllegalArgument),
+			req.T("lang.unknown", i18n.Vars{"Locales": strings.Join(b.messages.Locales(), ", ")}))
+	}
+	tr := b.messages.Translator(locale)
+
+	if req.args.String("scope") == "campaign" {
+		role, err := b.role(req)
+		if err != nil {
+			return simpleErr(fmt.Errorf("cannot read role: %w", err), req.T("lang.error"))
+		}
+		if !role.Includes(db.RoleGameMaster) {
+			return simpleErr(errNotGameMaster, req.T("permission.not_game_master"))
+		}
+
+		req.Campaign.Locale = locale
+		if err := b.saveCampaign(req.Campaign); err != nil {
+			return simpleErr(fmt.Errorf("cannot save campaign: %w", err), req.T("lang.error"))
+		}
+		return simpleResponse(tr.T("lang.campaign_done", i18n.Vars{"Name": tr.T("locale.name")}))
+	}
+
+	req.Player.Locale = locale
+	if err := b.db.SavePlayer(req.Player); err != nil {
+		return simpleErr(fmt.Errorf("cannot save player: %w", err), req.T("lang.error"))
+	}
+	return simpleResponse(tr.T("lang.done", i18n.Vars{"Name": tr.T("locale.name")}))
+}
+
+func validPrefix(prefix string) bool {
+	if prefix == "" || utf8.RuneCountInString(prefix) > maxPrefixLength {
+		return false
+	}
+	// a mention would be mistaken for the prefix, a quote for a text
+	if strings.HasPrefix(prefix, "<") || strings.ContainsRune(prefix, '"') {
+		return false
+	}
+	return strings.IndexFunc(prefix, unicode.IsSpace) < 0
+}
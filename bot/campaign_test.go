@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCampaignsAreIndependent(t *testing.T) {
+	h := newHarness(t)
+
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_1")
+
+	other := "other-guild"
+	expectContains(t, h.sayIn(other, 10, "!character")[0], "rejoindre l'aventure")
+	expectContains(t, h.sayIn(other, 10, "!watch")[0], "Il n'y a plus de monstre")
+	expectContains(t, h.sayIn(other, 10, "!join_adventure mage")[0], "en tant que Mage")
+	expectContains(t, h.sayIn(other, 10, "!characters")[0], "<@10> (niv. 1)")
+
+	expectContains(t, h.sayOne(10, "!character"), "<@10> (Combattant)")
+	expectContains(t, h.sayOne(10, "!watch"), "Rat - 11 / 11 HP")
+}
+
+func TestOnlyCommandsCreateACampaign(t *testing.T) {
+	h := newHarness(t)
+
+	h.sayIn("other-guild", 10, "hello")
+	h.sayIn("other-guild", 10, "!dance")
+	if _, err := h.store.FetchCampaign("other-guild"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected no campaign before a command, got %v", err)
+	}
+
+	h.sayIn("other-guild", 10, "!characters")
+	if _, err := h.store.FetchCampaign("other-guild"); err != nil {
+		t.Errorf("expected a campaign on the first command, got %v", err)
+	}
+}
+
+func TestDirectMessagesPlayTheLastCampaign(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayIn("", 10, "!character")[0], "Rejoignez d'abord une aventure")
+
+	h.sayOne(10, "!join_adventure")
+	h.sayIn("other-guild", 10, "!join_adventure mage")
+	expectContains(t, h.sayIn("", 10, "!character")[0], "<@10> (Mage)")
+}
+
+func TestCampaignGameMaster(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(10, "!gm add <@10>"), "Vous n'êtes pas maître du jeu")
+	expectContains(t, h.sayOne(testGameMaster, "!gm add <@10>"), "<@10> is now gm")
+	expectContains(t, h.sayOne(10, "!spawn Rat_10_1_1_1_1"), "Monstre apparu")
+
+	// the game master of a campaign has no power over the others
+	expectContains(t, h.sayIn("other-guild", 10, "!spawn Rat_10_1_1_1_1")[0], "Vous n'êtes pas maître du jeu")
+}
+
+func TestPrefix(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(testGameMaster, "!prefix"), "Commands start with `!`")
+	expectContains(t, h.sayOne(testGameMaster, "!prefix <@1>"), "Invalid prefix")
+	expectContains(t, h.sayOne(10, "!prefix ?"), "Vous n'êtes pas maître du jeu")
+	expectContains(t, h.sayOne(testGameMaster, "!prefix ?"), "Commands now start with `?`, for example `?help`")
+
+	if answers := h.say(10, "!join_adventure"); len(answers) != 0 {
+		t.Errorf("the old prefix still works: %q", answers)
+	}
+	expectContains(t, h.sayOne(10, "?join_adventure"), "<@10> a rejoint l'aventure !")
+	expectContains(t, h.sayOne(10, "<@500> character"), "<@10> (Combattant)")
+	expectContains(t, h.sayOne(10, "?help str"), "`?str <points> [--dry-run]`")
+	expectContains(t, h.sayOne(10, "?str"), "essayez `?str 1`")
+
+	// the prefix is saved with the campaign
+	h.bot.campaigns = campaignCache{}
+	expectContains(t, h.sayOne(10, "?fiche"), "<@10> (Combattant)")
+	expectContains(t, h.sayOne(testGameMaster, "<@500> prefix !"), "Commands now start with `!`")
+}
+
+func TestLang(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(10, "!lang"), "Vous jouez en français. Langues disponibles : `en` (English), `fr` (français)")
+	expectContains(t, h.sayOne(10, "!lang de"), "Langue inconnue")
+	expectContains(t, h.sayOne(10, "!lang EN"), "You now play in English.")
+	expectContains(t, h.sayOne(10, "!join_adventure"), "<@10> joined the adventure!")
+
+	// the other players keep the language of the campaign
+	expectContains(t, h.sayOne(11, "!join_adventure"), "<@11> a rejoint l'aventure !")
+	expectContains(t, h.sayOne(11, "!lang en campaign"), "Vous n'êtes pas maître du jeu")
+	expectContains(t, h.sayOne(testGameMaster, "!lang en campaign"), "The campaign is now played in English.")
+	expectContains(t, h.sayOne(11, "!character"), "Stamina: 100 / 100")
+
+	// the language of the player wins over the one of the campaign
+	h.sayOne(10, "!lang fr")
+	expectContains(t, h.sayOne(10, "!character"), "Endurance : 100 / 100")
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// channelScope lists where a command can be played while an adventure is running
+type channelScope int
+
+const (
+	inAdventureChannel channelScope = 1 << iota
+	inDirectMessages
+	inAnyChannel
+
+	inAdventureOrDirectMessages = inAdventureChannel | inDirectMessages
+	anywhere                    = inAdventureChannel | inDirectMessages | inAnyChannel
+)
+
+// checkChannel redirects the players to the adventure channel when the command cannot be played here.
+// Nothing is enforced before the adventure starts, and game masters play anywhere.
+func checkChannel(cmd *_Command, req *Request, adventure *db.Adventure, role db.Role) *_Response {
+	if !adventure.IsRunning() || role.Includes(db.RoleGameMaster) {
+		return nil
+	}
+
+	scope := cmd.Channels
+
+	switch {
+	case req.ChannelID == adventure.ChannelID:
+		if scope&inAdventureChannel != 0 {
+			return nil
+		}
+	case req.GuildID == "":
+		if scope&inDirectMessages != 0 {
+			return nil
+		}
+	default:
+		if scope&inAnyChannel != 0 {
+			return nil
+		}
+	}
+
+	key := "channel.adventure"
+	if scope&inDirectMessages != 0 {
+		key = "channel.adventure_or_dm"
+	}
+	resp := simpleResponse(req.T(key, i18n.Vars{"Channel": util.ChannelIDToText(adventure.ChannelID)}))
+	return &resp
+}
@@ -0,0 +1,30 @@
+package bot
+
+import (
+	"testing"
+)
+
+func TestAdventureChannelIsEnforced(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+
+	// nothing is enforced before the adventure starts
+	expectContains(t, h.sayOn(testGuild, "tavern", 10, "!watch")[0], "Il n'y a plus de monstre")
+
+	h.sayOne(testGameMaster, "!start_adventure")
+	h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_1")
+
+	expectContains(t, h.sayOn(testGuild, "tavern", 10, "!hit")[0], "Cette commande se joue dans <#adventure>, à tout de suite !")
+	expectContains(t, h.sayOn(testGuild, "tavern", 10, "!character")[0],
+		"Cette commande se joue dans <#adventure> ou en message privé")
+	expectContains(t, h.sayOn(testGuild, "tavern", 10, "!adventure_status")[0], "Aventure en cours")
+
+	// direct messages
+	expectContains(t, h.sayOn("", "dm", 10, "!character")[0], "<@10> (Combattant)")
+	expectContains(t, h.sayOn("", "dm", 10, "!hit")[0], "Cette commande se joue dans <#adventure>")
+
+	expectContains(t, h.sayOne(10, "!hit"), "**<@10>** inflige ")
+
+	// the game master plays anywhere
+	expectContains(t, h.sayOn(testGuild, "tavern", testGameMaster, "!watch")[0], "Rat")
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+const healStaminaCost = 15
+
+// triggerMageAction casts a spell: wisdom based, resisted by the monster wisdom
+func (b *Bot) triggerMageAction(tx db.Store, tr i18n.Translator, attacker *db.Character, monster *db.Monster) (bool, string, error) {
+	wisdomBonus, err := b.roll(tx, monster, attacker.ID, attacker.Wisdom*2+1)
+	if err != nil {
+		return false, "", err
+	}
+	damage := attacker.Wisdom + wisdomBonus - monster.Wisdom
+	if damage <= 0 { // At least 1 damage
+		damage = 1
+	}
+
+	endOfFight, err := damageMonster(tx, monster, db.BattleEvent{
+		Action:  db.ActionSpell,
+		ActorID: attacker.ID,
+		Roll:    wisdomBonus,
+		Damage:  damage,
+	})
+	if err != nil {
+		return false, "", err
+	}
+
+	actionReport := writeMageActionReport(tr, attacker, monster, damage, wisdomBonus)
+	return endOfFight, actionReport, nil
+}
+
+func writeMageActionReport(tr i18n.Translator, attacker *db.Character, monster *db.Monster, damage int, wisdomBonus int) string {
+	return tr.T("battle.mage", i18n.Vars{
+		"Player":  util.DiscordIDToText(attacker.UserID),
+		"Damage":  damage,
+		"Formula": strconv.Itoa(attacker.Wisdom) + "+" + strconv.Itoa(wisdomBonus) + "-" + strconv.Itoa(monster.Wisdom),
+		"Monster": monster.Name,
+	}) + "\n"
+}
+
+// triggerHealerAction strikes with a staff: strength based, with a small wisdom bonus
+func (b *Bot) triggerHealerAction(tx db.Store, tr i18n.Translator, attacker *db.Character, monster *db.Monster) (bool, string, error) {
+	wisdomBonus, err := b.roll(tx, monster, attacker.ID, attacker.Wisdom+1)
+	if err != nil {
+		return false, "", err
+	}
+	weaponBonus, _, err := b.equipmentBonus(tx, attacker.ID)
+	if err != nil {
+		return false, "", err
+	}
+	damage := attacker.Strength + weaponBonus + wisdomBonus - monster.Agility
+	if damage <= 0 { // At least 1 damage
+		damage = 1
+	}
+
+	endOfFight, err := damageMonster(tx, monster, db.BattleEvent{
+		Action:  db.ActionStaff,
+		ActorID: attacker.ID,
+		Roll:    wisdomBonus,
+		Damage:  damage,
+	})
+	if err != nil {
+		return false, "", err
+	}
+
+	actionReport := writeHealerActionReport(tr, attacker, monster, damage, wisdomBonus, weaponBonus)
+	return endOfFight, actionReport, nil
+}
+
+func writeHealerActionReport(tr i18n.Translator, attacker *db.Character, monster *db.Monster,
+	damage int, wisdomBonus int, weaponBonus int) string {
+	return tr.T("battle.healer", i18n.Vars{
+		"Player": util.DiscordIDToText(attacker.UserID),
+		"Damage": damage,
+		"Formula": strconv.Itoa(attacker.Strength) + writeBonus(weaponBonus) + "+" + strconv.Itoa(wisdomBonus) +
+			"-" + strconv.Itoa(monster.Agility),
+		"Monster": monster.Name,
+	}) + "\n"
+}
+
+// damageRange returns the lowest and the highest damage of the character, before the defense of the monster
+func damageRange(c *db.Character, weaponBonus int) (int, int) {
+	switch c.Class {
+	case db.ClassMage:
+		return c.Wisdom, c.Wisdom * 3
+	case db.ClassHealer:
+		return c.Strength + weaponBonus, c.Strength + weaponBonus + c.Wisdom
+	}
+	return c.Strength + weaponBonus, c.Strength + weaponBonus + c.Agility*2
+}
+
+// healAlly makes a healer restore twice their wisdom in HP to a character, reviving them if knocked out
+func (b *Bot) healAlly(tr i18n.Translator, campaignID uint, healerID uint, targetID uint) (string, error) {
+	tx := b.db.Begin()
+	defer tx.Rollback()
+
+	healer, err := tx.FetchCharacterInfo(campaignID, healerID)
+	if err != nil {
+		return "", err
+	}
+
+	if healer.Class != db.ClassHealer {
+		return "", errWrongClass
+	}
+
+	if healer.IsKnockedOut() {
+		return "", errKnockedOut
+	}
+
+	if err := b.spendStamina(tx, &healer, healStaminaCost); err != nil {
+		return "", err
+	}
+
+	target := healer
+	if targetID != healerID {
+		target, err = tx.FetchCharacterInfo(campaignID, targetID)
+		if err != nil {
+			return "", err
+		}
+		target.Regenerate(b.progression, b.now())
+	}
+
+	revived := target.IsKnockedOut()
+	heal := healer.Wisdom * 2
+	if target.CurrentHp+heal > target.GetMaxHP(b.progression) {
+		heal = target.GetMaxHP(b.progression) - target.CurrentHp
+	}
+	target.CurrentHp += heal
+
+	if err := tx.UpdateCharacter(&target, db.RegenColumns...); err != nil {
+		return "", err
+	}
+
+	// a heal in the middle of a fight is part of the battle log of the target
+	monster, err := tx.FetchFoughtMonster(campaignID, target.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	if err == nil {
+		if err := tx.RecordEvent(&monster, db.BattleEvent{
+			Action:   db.ActionHeal,
+			ActorID:  healer.ID,
+			TargetID: target.ID,
+			Heal:     heal,
+			HP:       target.CurrentHp,
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	return writeHealReport(tr, b.progression, &healer, &target, heal, revived), tx.Commit()
+}
+
+func writeHealReport(tr i18n.Translator, curve *progression.Curve, healer *db.Character, target *db.Character, heal int, revived bool) string {
+	report := tr.N("heal.done", heal, i18n.Vars{
+		"Healer": util.DiscordIDToText(healer.UserID),
+		"Player": util.DiscordIDToText(target.UserID),
+		"HP":     target.CurrentHp,
+		"MaxHP":  target.GetMaxHP(curve),
+	}) + "\n"
+
+	if revived && !target.IsKnockedOut() {
+		report += tr.T("heal.revived", i18n.Vars{"Player": util.DiscordIDToText(target.UserID)}) + "\n"
+	}
+
+	return report
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+)
+
+func TestClassSelection(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(10, "!join_adventure bard"), "Classe inconnue")
+	expectContains(t, h.sayOne(10, "!join_adventure mage"), "<@10> a rejoint l'aventure en tant que Mage !")
+	expectContains(t, h.sayOne(10, "!class healer"), "Vous avez déjà choisi votre classe.")
+
+	expectContains(t, h.sayOne(11, "!join_adventure"), "Choisissez votre classe avec !class")
+	expectContains(t, h.sayOne(11, "!character"), "<@11> (Combattant)")
+	expectContains(t, h.sayOne(11, "!class"), "Mauvaise syntaxe (classe manquant), essayez `!class soigneur`")
+	expectContains(t, h.sayOne(11, "!class bard"), "Choisissez votre classe parmi")
+	expectContains(t, h.sayOne(11, "!class Soigneur"), "<@11> devient Soigneur !")
+	expectContains(t, h.sayOne(11, "!class mage"), "Vous avez déjà choisi votre classe.")
+
+	if c := h.character(11); c.Class != db.ClassHealer {
+		t.Errorf("expected a healer, got %q", c.Class)
+	}
+}
+
+func TestMageSpell(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure mage")
+	h.sayOne(10, "!wis 5")
+	h.sayOne(testGameMaster, "!spawn Golem_0_0_50_2_1000")
+
+	report := h.sayOne(10, "!hit")
+	expectContains(t, report, "**<@10>** lance un sort et inflige ")
+
+	// wisdom 6 + bonus in [0,12] - monster wisdom 2, agility is ignored
+	m, err := h.store.FetchMonsterInfo(h.campaign())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if damage := m.GetMaxHP() - m.CurrentHp; damage < 4 || damage > 16 {
+		t.Errorf("unexpected damage %d: %q", damage, report)
+	}
+}
+
+func TestHealerStrikesAndHeals(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(11, "!join_adventure soigneur")
+	h.sayOne(11, "!wis 5")
+	h.sayOne(testGameMaster, "!spawn Ogre_10_20_0_1_100")
+
+	expectContains(t, h.sayOne(10, "!hit"), "<@10> est K.O. !")
+
+	expectContains(t, h.sayOne(10, "!heal <@11>"), "Seul un Soigneur peut soigner.")
+	expectContains(t, h.sayOne(11, "!heal <@99>"), "Ce personnage n'existe pas.")
+
+	report := h.sayOne(11, "!heal <@10>")
+	expectContains(t, report, "**<@11>** soigne **<@10>** de 12 points de vie (12 / 12 HP).")
+	expectContains(t, report, "<@10> se relève !")
+
+	expectContains(t, h.sayOne(11, "!hit"), "**<@11>** frappe de son bâton et inflige ")
+}
+
+func TestUnknownClassCannotAttack(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_1")
+
+	c := h.character(10)
+	c.Class = "Barde"
+	if err := h.store.SaveCharacter(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	expectContains(t, h.sayOne(10, "!hit"), "Impossible d'attaquer.")
+	if c := h.character(10); c.Stamina != db.MaxStamina {
+		t.Errorf("expected no stamina spent, got %d", c.Stamina)
+	}
+}
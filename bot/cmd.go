@@ -6,146 +6,830 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/bwmarrin/discordgo"
 	"gorm.io/gorm"
 
+	"github.com/vincent-heng/discord-airpgbot/bot/bestiary"
 	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/items"
 	"github.com/vincent-heng/discord-airpgbot/bot/util"
 )
 
-func (b *Bot) charactersCmd(s *discordgo.Session, m *discordgo.MessageCreate, _ uint) _Response {
-	characters, err := b.db.FetchCharacters()
+func (b *Bot) charactersCmd(req *Request) _Response {
+	characters, err := b.db.FetchCharacters(req.Campaign.ID)
 	if err != nil {
-		return simpleErr(err, "Impossible de récupérer la liste.")
+		return simpleErr(err, req.T("characters.error"))
 	}
-	return simpleResponse(characters)
+
+	if len(characters) == 0 {
+		return simpleResponse(req.T("characters.none"))
+	}
+
+	list := []string{}
+	for i := range characters {
+		list = append(list, req.T("characters.entry", i18n.Vars{
+			"Player": util.DiscordIDToText(characters[i].UserID),
+			"Level":  characters[i].Level,
+		}))
+	}
+	return simpleResponse(strings.Join(list, " "))
 }
 
-func (b *Bot) joinAdventure(s *discordgo.Session, m *discordgo.MessageCreate, authorID uint) _Response {
-	if err := b.db.CreateCharacter(authorID); err != nil {
-		return simpleErr(fmt.Errorf("cannot create character: %w", err),
-			"Impossible de créer le personnage...")
+// className writes the name of a class in the language of the translator
+func className(tr i18n.Translator, class string) string {
+	return tr.T("class.name." + class)
+}
+
+func classList(tr i18n.Translator) string {
+	names := []string{}
+	for _, class := range db.Classes {
+		names = append(names, className(tr, class))
 	}
+	return "`" + strings.Join(names, "`, `") + "`"
+}
 
-	return simpleResponse(util.DiscordIDToText(authorID) + " a rejoint l'aventure !")
+func (b *Bot) joinAdventure(req *Request) _Response {
+	class := ""
+	if name := req.args.String("class"); name != "" {
+		c, ok := db.ParseClass(name)
+		if !ok {
+			return simpleErr(fmt.Errorf("unknown class %q: %w", name, errIllegalArgument),
+				req.T("join.unknown_class", i18n.Vars{"Classes": classList(req.tr)}))
+		}
+		class = c
+	}
+
+	if err := b.db.CreateCharacter(b.progression, req.Campaign.ID, req.AuthorID, class); err != nil {
+		return simpleErr(fmt.Errorf("cannot create character: %w", err), req.T("join.error"))
+	}
+
+	player := util.DiscordIDToText(req.AuthorID)
+	if class == "" {
+		return simpleResponse(req.T("join.no_class", i18n.Vars{"Player": player, "Classes": classList(req.tr)}))
+	}
+
+	return simpleResponse(req.T("join.done", i18n.Vars{"Player": player, "Class": className(req.tr, class)}))
 }
 
-func (b *Bot) characterCmd(s *discordgo.Session, m *discordgo.MessageCreate, authorID uint) _Response {
-	c, err := b.db.FetchCharacterInfo(authorID)
-	if err != nil {
-		return simpleErr(fmt.Errorf("cannot fetch character info: %w", err),
-			"Impossible de récupérer les informations du personnage.")
+func (b *Bot) classCmd(req *Request) _Response {
+	name := req.args.String("class")
+	class, ok := db.ParseClass(name)
+	if !ok {
+		return simpleErr(fmt.Errorf("unknown class %q: %w", name, errIllegalArgument),
+			req.T("class.choose", i18n.Vars{"Classes": classList(req.tr)}))
 	}
 
-	if c.ID == 0 {
-		return simpleErr(fmt.Errorf("id: %v, name: %v, err: %w", authorID, m.Author.Username, errCharacterDoesNotExist),
-			"Vous devez d'abord rejoindre l'aventure en tapant !join_adventure")
+	if err := b.db.ChooseClass(req.Campaign.ID, req.AuthorID, class); err != nil {
+		if errors.Is(err, db.ErrClassAlreadyChosen) {
+			return simpleErr(err, req.T("class.already_chosen"))
+		}
+		return simpleErr(fmt.Errorf("cannot choose class: %w", err), req.T("class.error"))
 	}
 
-	return simpleResponse(c.String())
+	return simpleResponse(req.T("class.done", i18n.Vars{
+		"Player": util.DiscordIDToText(req.AuthorID),
+		"Class":  className(req.tr, class),
+	}))
 }
 
-func (b *Bot) watchCmd(s *discordgo.Session, m *discordgo.MessageCreate, _ uint) _Response {
-	monster, err := b.db.FetchMonsterInfo()
+// fetchCharacter loads the character of the author in the campaign, or returns the error response
+func (b *Bot) fetchCharacter(req *Request) (*db.Character, *_Response) {
+	c, err := b.db.FetchCharacterInfo(req.Campaign.ID, req.AuthorID)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return simpleResponse("Il n'y a plus de monstre... pour l'instant !")
+		resp := simpleErr(fmt.Errorf("id: %v, name: %v, err: %w", req.AuthorID, req.AuthorName, errCharacterDoesNotExist),
+			req.T("character.missing"))
+		return nil, &resp
+	}
+	if err != nil {
+		resp := simpleErr(fmt.Errorf("cannot fetch character info: %w", err), req.T("character.error"))
+		return nil, &resp
+	}
+	return &c, nil
+}
+
+func (b *Bot) characterCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	c.Regenerate(b.progression, b.now())
+	return messageResponse(characterMessage(req.tr, b.progression, c, req.AuthorAvatar))
+}
+
+// xpCmd shows the progress of the character toward the next level
+func (b *Bot) xpCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	vars := i18n.Vars{
+		"Player":     util.DiscordIDToText(c.UserID),
+		"Level":      c.Level,
+		"Experience": c.Experience,
+	}
+	next, ok := b.progression.Threshold(c.Level + 1)
+	if !ok {
+		return simpleResponse(req.T("xp.max_level", vars))
+	}
+	current, _ := b.progression.Threshold(c.Level)
+
+	vars["Next"] = c.Level + 1
+	vars["Threshold"] = next
+	// the levels are only gained on victories, after the curve changed the character can be ahead of it
+	vars["Missing"] = 0
+	if c.Experience < next {
+		vars["Missing"] = next - c.Experience
+	}
+	vars["Bar"] = util.ProgressBar(c.Experience-current, next-current, barWidth)
+	return simpleResponse(req.T("xp.progress", vars))
+}
+
+func (b *Bot) watchCmd(req *Request) _Response {
+	monsters, err := b.db.FetchMonsters(req.Campaign.ID)
+	if err != nil {
+		return simpleErr(err, req.T("monsters.error"))
 	}
 
-	if err == nil {
-		return simpleResponse(monster.String())
+	if len(monsters) == 0 {
+		return simpleResponse(req.T("monsters.none"))
 	}
 
-	return simpleErr(err, "Impossible de récupérer les informations du monstre actuel.")
+	if len(monsters) == 1 {
+		return messageResponse(monsterMessage(req.tr, &monsters[0], b.monsterImage(&monsters[0])))
+	}
+	return messageResponse(encounterMessage(req.tr, monsters))
 }
 
-func (b *Bot) hitCmd(s *discordgo.Session, m *discordgo.MessageCreate, authorID uint) _Response {
-	report, err := b.attackCurrentMonster(authorID)
+// monsterImage finds the picture of the monster in the bestiary, if any
+func (b *Bot) monsterImage(m *db.Monster) string {
+	if m.Template == "" {
+		return ""
+	}
+
+	template, err := b.bestiary.Get(m.Template)
+	if err != nil {
+		return ""
+	}
+	return template.Image
+}
+
+func (b *Bot) hitCmd(req *Request) _Response {
+	report, err := b.attackMonster(req.tr, req.Campaign, req.AuthorID, req.args.String("target"))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return simpleResponse("Il n'y a plus de monstre... pour l'instant !")
+			return simpleResponse(req.T("monsters.none"))
+		}
+		if errors.Is(err, db.ErrUnknownTarget) {
+			return simpleErr(err, req.T("hit.unknown_target"))
+		}
+		if errors.Is(err, errKnockedOut) {
+			return simpleErr(err, req.T("hit.knocked_out"))
+		}
+		if errors.Is(err, errNotEnoughStamina) {
+			return simpleErr(err, req.T("hit.exhausted", i18n.Vars{"Cost": hitStaminaCost}))
+		}
+		return simpleErr(fmt.Errorf("cannot attack monster: %w", err), req.T("hit.error"))
+	}
+
+	return simpleResponse(report)
+}
+
+func (b *Bot) healCmd(req *Request) _Response {
+	report, err := b.healAlly(req.tr, req.Campaign.ID, req.AuthorID, req.args.User("@player"))
+	if err != nil {
+		switch {
+		case errors.Is(err, errWrongClass):
+			return simpleErr(err, req.T("heal.wrong_class", i18n.Vars{"Class": className(req.tr, db.ClassHealer)}))
+		case errors.Is(err, errKnockedOut):
+			return simpleErr(err, req.T("character.knocked_out"))
+		case errors.Is(err, errNotEnoughStamina):
+			return simpleErr(err, req.T("heal.exhausted", i18n.Vars{"Cost": healStaminaCost}))
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return simpleErr(err, req.T("heal.unknown_character"))
 		}
-		return simpleErr(fmt.Errorf("cannot attack monster: %w", err), "Impossible d'attaquer.")
+		return simpleErr(fmt.Errorf("cannot heal: %w", err), req.T("heal.error"))
 	}
 
 	return simpleResponse(report)
 }
 
-func (b *Bot) handleUpStats(s *discordgo.Session, m *discordgo.MessageCreate, userID uint, stat string) _Response {
-	statTrigram := stat[0:3]
-	content := strings.TrimSpace(strings.TrimPrefix(m.Content, "!"+statTrigram+" "))
-	amount, err := strconv.Atoi(content)
+func (b *Bot) inventoryCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	inventory, err := b.db.FetchInventory(c.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch inventory: %w", err), req.T("inventory.error"))
+	}
+
+	return simpleResponse(b.writeInventory(req.tr, req.AuthorID, inventory))
+}
+
+// findItem looks for an item of the catalog, or returns the error response
+func (b *Bot) findItem(req *Request, name string) (*items.Item, *_Response) {
+	if name == "" {
+		resp := simpleErr(fmt.Errorf("no item: %w", errIllegalArgument), req.T("item.missing"))
+		return nil, &resp
+	}
+
+	item, err := b.items.Get(name)
+	if err != nil {
+		resp := simpleErr(err, req.T("item.unknown", i18n.Vars{"Item": name}))
+		return nil, &resp
+	}
+	return &item, nil
+}
+
+func (b *Bot) equipCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	item, resp := b.findItem(req, req.args.String("item"))
+	if resp != nil {
+		return *resp
+	}
+
+	if !item.Equipable() {
+		return simpleErr(fmt.Errorf("%s: %w", item.Key, errIllegalArgument),
+			req.T("equip.not_equipable", i18n.Vars{"Item": item.Name}))
+	}
+
+	if err := b.equipItem(c.ID, item); err != nil {
+		if errors.Is(err, db.ErrItemNotOwned) {
+			return simpleErr(err, req.T("item.not_owned", i18n.Vars{"Item": item.Name}))
+		}
+		return simpleErr(fmt.Errorf("cannot equip: %w", err), req.T("equip.error"))
+	}
+
+	return simpleResponse(req.T("equip.done", i18n.Vars{"Player": util.DiscordIDToText(req.AuthorID), "Item": item.Name}))
+}
+
+func (b *Bot) unequipCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	item, resp := b.findItem(req, req.args.String("item"))
+	if resp != nil {
+		return *resp
+	}
+
+	if err := b.db.SetEquipped(c.ID, item.Key, false); err != nil {
+		if errors.Is(err, db.ErrItemNotOwned) {
+			return simpleErr(err, req.T("item.not_owned", i18n.Vars{"Item": item.Name}))
+		}
+		return simpleErr(fmt.Errorf("cannot unequip: %w", err), req.T("unequip.error"))
+	}
+
+	return simpleResponse(req.T("unequip.done", i18n.Vars{"Player": util.DiscordIDToText(req.AuthorID), "Item": item.Name}))
+}
+
+func (b *Bot) useCmd(req *Request) _Response {
+	item, resp := b.findItem(req, req.args.String("item"))
+	if resp != nil {
+		return *resp
+	}
+
+	if item.Kind != items.Consumable {
+		return simpleErr(fmt.Errorf("%s: %w", item.Key, errIllegalArgument),
+			req.T("use.not_consumable", i18n.Vars{"Item": item.Name}))
+	}
+
+	c, err := b.useItem(req.Campaign.ID, req.AuthorID, item)
+	if err != nil {
+		if errors.Is(err, db.ErrItemNotOwned) {
+			return simpleErr(err, req.T("item.not_owned", i18n.Vars{"Item": item.Name}))
+		}
+		if errors.Is(err, errKnockedOut) {
+			return simpleErr(err, req.T("character.knocked_out"))
+		}
+		return simpleErr(fmt.Errorf("cannot use item: %w", err), req.T("use.error"))
+	}
+
+	return simpleResponse(req.T("use.done", i18n.Vars{
+		"Player":     util.DiscordIDToText(req.AuthorID),
+		"Item":       item.Name,
+		"HP":         c.CurrentHp,
+		"MaxHP":      c.GetMaxHP(b.progression),
+		"Stamina":    c.Stamina,
+		"MaxStamina": db.MaxStamina,
+	}))
+}
+
+// dropCmd throws items away: !drop <item> [quantity]
+func (b *Bot) dropCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	quantity := req.args.IntOr("quantity", 1)
+	item, resp := b.findItem(req, req.args.String("item"))
+	if resp != nil {
+		return *resp
+	}
+
+	if err := b.db.RemoveItem(c.ID, item.Key, quantity); err != nil {
+		if errors.Is(err, db.ErrItemNotOwned) {
+			return simpleErr(err, req.T("item.not_enough", i18n.Vars{"Item": item.Name}))
+		}
+		return simpleErr(fmt.Errorf("cannot drop: %w", err), req.T("drop.error"))
+	}
+
+	return simpleResponse(req.T("drop.done", i18n.Vars{
+		"Player":   util.DiscordIDToText(req.AuthorID),
+		"Quantity": quantity,
+		"Item":     item.Name,
+	}))
+}
+
+func (b *Bot) shopCmd(req *Request) _Response {
+	shop, err := b.db.FetchShop(req.Campaign.ID)
 	if err != nil {
-		return simpleErr(fmt.Errorf("cannot upgrading stats: %w", errIllegalArgument),
-			"Mauvaise syntaxe, essayez `!"+statTrigram+" 1`")
+		return simpleErr(fmt.Errorf("cannot fetch shop: %w", err), req.T("shop.error"))
 	}
 
-	if amount <= 0 {
-		return simpleErr(fmt.Errorf("cannot upgrading stats negative value: %w",
-			errIllegalArgument), "Mauvaise syntaxe, essayez un nombre positif :unamused:")
+	if len(shop) == 0 {
+		return simpleResponse(req.T("shop.empty"))
 	}
 
-	if e := b.db.UpStats(stat, userID, amount); e != nil {
-		return simpleErr(fmt.Errorf("cannot upgrade stat: %w", e), "Répartition impossible.")
+	str := req.T("shop.title") + "\n"
+	for i := range shop {
+		shopItem := &shop[i]
+		str += req.T("shop.item", i18n.Vars{"Item": b.items.Name(shopItem.Item), "Price": shopItem.Price})
+		if shopItem.Stock != db.UnlimitedStock {
+			str += req.T("shop.stock", i18n.Vars{"Stock": shopItem.Stock})
+		}
+		if item, err := b.items.Get(shopItem.Item); err == nil {
+			str += writeItemEffects(req.tr, &item)
+		}
+		str += "\n"
 	}
+	return simpleResponse(str)
+}
 
-	return simpleResponse("Répartition effectuée !")
+// tradeErr answers the errors of !buy and !sell
+func tradeErr(req *Request, err error, item *items.Item) _Response {
+	vars := i18n.Vars{"Item": item.Name}
+	switch {
+	case errors.Is(err, db.ErrNotForSale):
+		return simpleErr(err, req.T("trade.not_for_sale", vars))
+	case errors.Is(err, db.ErrOutOfStock):
+		return simpleErr(err, req.T("trade.out_of_stock", vars))
+	case errors.Is(err, db.ErrNotEnoughGold):
+		return simpleErr(err, req.T("trade.not_enough_gold"))
+	case errors.Is(err, db.ErrItemNotOwned):
+		return simpleErr(err, req.T("item.not_enough", vars))
+	}
+	return simpleErr(fmt.Errorf("cannot trade: %w", err), req.T("trade.error"))
 }
 
-func (b *Bot) startAdventureCmd(s *discordgo.Session, m *discordgo.MessageCreate, _ uint) _Response {
-	if err := util.SetAdventureChannel(m.ChannelID); err != nil {
-		return simpleErr(fmt.Errorf("cannot set adventure: %w", err), "")
+// buyCmd buys items from the shop: !buy <item> [quantity]
+func (b *Bot) buyCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	quantity := req.args.IntOr("quantity", 1)
+	item, resp := b.findItem(req, req.args.String("item"))
+	if resp != nil {
+		return *resp
+	}
+
+	price, err := b.db.Buy(req.Campaign.ID, c.ID, item.Key, quantity)
+	if err != nil {
+		return tradeErr(req, err, item)
 	}
 
-	return simpleResponse("L'aventure commence ici.")
+	return simpleResponse(req.T("buy.done", i18n.Vars{
+		"Player":   util.DiscordIDToText(req.AuthorID),
+		"Quantity": quantity,
+		"Item":     item.Name,
+		"Price":    price,
+	}))
 }
 
-func (b *Bot) shoutCmd(s *discordgo.Session, m *discordgo.MessageCreate, _ uint) _Response {
-	content := strings.TrimSpace(strings.TrimPrefix(m.Content, "!shout "))
+// sellCmd sells items to the shop, for half their price: !sell <item> [quantity]
+func (b *Bot) sellCmd(req *Request) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	quantity := req.args.IntOr("quantity", 1)
+	item, resp := b.findItem(req, req.args.String("item"))
+	if resp != nil {
+		return *resp
+	}
 
-	channelID, err := util.GetChannelID()
+	price, err := b.db.Sell(req.Campaign.ID, c.ID, item.Key, quantity)
 	if err != nil {
-		return simpleErr(fmt.Errorf("cannot get channel ID: %w", err),
-			"Error retrieving channel ID")
+		return tradeErr(req, err, item)
 	}
 
-	if channelID == "" {
-		return simpleResponse("Set the channel with !start_adventure")
+	return simpleResponse(req.T("sell.done", i18n.Vars{
+		"Player":   util.DiscordIDToText(req.AuthorID),
+		"Quantity": quantity,
+		"Item":     item.Name,
+		"Price":    price,
+	}))
+}
+
+func (b *Bot) restCmd(req *Request) _Response {
+	c, err := b.healCharacter(req.Campaign.ID, req.AuthorID, false)
+	if err != nil {
+		if errors.Is(err, errFightInProgress) {
+			return simpleErr(err, req.T("rest.fight"))
+		}
+		return simpleErr(fmt.Errorf("cannot rest: %w", err), req.T("rest.error"))
+	}
+
+	return simpleResponse(req.T("rest.done", i18n.Vars{
+		"Player": util.DiscordIDToText(req.AuthorID),
+		"HP":     c.CurrentHp,
+		"MaxHP":  c.GetMaxHP(b.progression),
+	}))
+}
+
+// statArgColumns are the columns of the stat argument of !stats, by its choices
+var statArgColumns = map[string]string{ //nolint:gochecknoglobals
+	"str": "strength",
+	"agi": "agility",
+	"wis": "wisdom",
+	"con": "constitution",
+}
+
+// statsCmd spends skill points in the chosen stat, like !str and co
+func (b *Bot) statsCmd(req *Request) _Response {
+	return b.handleUpStats(req, statArgColumns[req.args.String("stat")])
+}
+
+func (b *Bot) handleUpStats(req *Request, stat string) _Response {
+	c, resp := b.fetchCharacter(req)
+	if resp != nil {
+		return *resp
+	}
+
+	if req.args.Flag("dry-run") {
+		return b.previewStats(req, c, stat)
+	}
+
+	if e := b.db.UpStats(b.progression, stat, req.Campaign.ID, req.AuthorID, req.args.Int("points")); e != nil {
+		return b.upStatsErr(req, e, c, stat)
+	}
+
+	return simpleResponse(req.T("stats.done"))
+}
+
+func (b *Bot) shoutCmd(req *Request) _Response {
+	a, err := b.adventure(req.Campaign.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch adventure: %w", err), req.T("adventure.error"))
+	}
+
+	if !a.IsRunning() {
+		return simpleResponse(req.T("shout.not_started"))
 	}
 
 	return _Response{
 		msgs: []_Message{
-			{Channel: channelID, Message: content},
+			{Channel: a.ChannelID, Message: req.Text},
 		},
 	}
 }
 
-func (b *Bot) spawnCmd(s *discordgo.Session, m *discordgo.MessageCreate, _ uint) _Response {
-	content := strings.TrimSpace(strings.TrimPrefix(m.Content, "!spawn "))
+// spawnArgs and spawnOptions are the schema of a monster of !spawn
+var (
+	spawnArgs    = []_Arg{{Name: "monster", Kind: argText}} //nolint:gochecknoglobals
+	spawnOptions = []_Arg{                                  //nolint:gochecknoglobals
+		{Name: "count", Kind: argInt, Range: between(1, maxSpawnCount)},
+		{Name: "xp", Kind: argInt, Range: atLeast(0)},
+		{Name: "gold", Kind: argInt, Range: atLeast(0)},
+		{Name: "str", Kind: argInt, Range: atLeast(0)},
+		{Name: "agi", Kind: argInt, Range: atLeast(0)},
+		{Name: "wis", Kind: argInt, Range: atLeast(0)},
+		{Name: "con", Kind: argInt, Range: atLeast(db.MinMonsterConstitution)},
+	}
+)
+
+// maxSpawnCount is the most monsters a !spawn brings, all its groups together
+const maxSpawnCount = 10
+
+// spawnCmd spawns an encounter: one or several monsters separated by ";".
+// A monster is a bestiary template with an optional count (goblin x3), and stats overriding the template
+// (goblin con=20). Other names are custom monsters ("Rat king" xp=10 str=1 con=5), or the legacy
+// Name of the mob_XP_str_agi_wis_con[_gold].
+func (b *Bot) spawnCmd(req *Request) _Response {
+	monsters := []db.Monster{}
+
+	for _, group := range splitOutsideQuotes(req.Text, ';') {
+		args, err := parseArgs(spawnArgs, spawnOptions, group)
+		if err != nil {
+			return badSyntax(err)
+		}
+
+		spawned, resp := b.parseSpawn(req, args)
+		if resp != nil {
+			return *resp
+		}
+		monsters = append(monsters, spawned...)
+	}
+
+	if len(monsters) > maxSpawnCount {
+		return simpleErr(fmt.Errorf("%d monsters: %w", len(monsters), errIllegalArgument),
+			req.T("spawn.too_many", i18n.Vars{"Max": maxSpawnCount}))
+	}
+	return b.spawnMonsters(req, monsters)
+}
+
+// parseSpawn instantiates the monsters of a group of !spawn
+func (b *Bot) parseSpawn(req *Request, args _Args) ([]db.Monster, *_Response) {
+	name := args.String("monster")
+	count := args.IntOr("count", 1)
+
+	// goblin x3
+	if words := strings.Fields(name); len(words) > 1 {
+		last := strings.ToLower(words[len(words)-1])
+		if n, err := strconv.Atoi(strings.TrimPrefix(last, "x")); err == nil && strings.HasPrefix(last, "x") {
+			if n < 1 || n > maxSpawnCount {
+				resp := simpleErr(fmt.Errorf("count %d: %w", n, errIllegalArgument),
+					req.T("spawn.bad_count", i18n.Vars{"Max": maxSpawnCount}))
+				return nil, &resp
+			}
+			name, count = strings.Join(words[:len(words)-1], " "), n
+		}
+	}
 
-	params := strings.Split(content, "_")
-	if len(params) < 6 {
-		return simpleErr(fmt.Errorf("syntax: Name of the mob_XP_str_agi_wis_con: %w", errIllegalArgument),
-			"Bad arguments. Syntax: Name of the mob_XP_str_agi_wis_con")
+	_m := db.Monster{Name: name}
+	custom := false
+	for _, option := range []string{"xp", "gold", "str", "agi", "wis", "con"} {
+		custom = custom || args.Has(option)
 	}
 
-	_m := db.Monster{
-		Name: params[0],
+	template, err := b.bestiary.Get(name)
+	switch {
+	case err == nil:
+		_m = db.Monster{
+			Name:         template.Name,
+			Template:     template.Key,
+			Experience:   template.Experience,
+			Gold:         template.Gold,
+			Strength:     template.Strength,
+			Agility:      template.Agility,
+			Wisdom:       template.Wisdom,
+			Constitution: template.Constitution,
+		}
+	case !errors.Is(err, bestiary.ErrUnknownTemplate):
+		resp := simpleErr(fmt.Errorf("cannot spawn %q: %w", name, err), req.T("bestiary.error"))
+		return nil, &resp
+	case !custom:
+		legacy, ok := parseLegacySpawn(name)
+		if !ok {
+			resp := simpleErr(fmt.Errorf("cannot spawn %q: %w", name, err), req.T("spawn.unknown_monster"))
+			return nil, &resp
+		}
+		_m = legacy
 	}
 
-	for i, ptr := range []*int{&_m.Experience, &_m.Strength, &_m.Agility, &_m.Wisdom, &_m.Constitution} {
-		value, err := strconv.Atoi(params[i+1])
+	_m.Experience = args.IntOr("xp", _m.Experience)
+	_m.Gold = args.IntOr("gold", _m.Gold)
+	_m.Strength = args.IntOr("str", _m.Strength)
+	_m.Agility = args.IntOr("agi", _m.Agility)
+	_m.Wisdom = args.IntOr("wis", _m.Wisdom)
+	_m.Constitution = args.IntOr("con", _m.Constitution)
+	if !_m.ValidStats() {
+		resp := simpleErr(fmt.Errorf("cannot spawn %q: %w", _m.Name, errIllegalArgument),
+			req.T("spawn.invalid_stats", i18n.Vars{"MinCon": db.MinMonsterConstitution}))
+		return nil, &resp
+	}
+
+	monsters := make([]db.Monster, 0, count)
+	for i := 0; i < count; i++ {
+		m := _m
+		if count > 1 {
+			m.Name += " " + strconv.Itoa(i+1)
+		}
+		m.CurrentHp = m.GetMaxHP()
+
+		monsters = append(monsters, m)
+	}
+	return monsters, nil
+}
+
+// parseLegacySpawn reads Name of the mob_XP_str_agi_wis_con[_gold], the name may contain underscores
+func parseLegacySpawn(text string) (db.Monster, bool) {
+	params := strings.Split(text, "_")
+
+	numbers := []int{}
+	for len(params) > 1 && len(numbers) < 6 {
+		n, err := strconv.Atoi(params[len(params)-1])
 		if err != nil {
-			// TODO improve msg
-			return simpleErr(fmt.Errorf("cannot spawn monster: %w", err), "Illegal argument")
+			break
+		}
+		numbers = append([]int{n}, numbers...)
+		params = params[:len(params)-1]
+	}
+	if len(numbers) < 5 {
+		return db.Monster{}, false
+	}
+
+	_m := db.Monster{Name: strings.Join(params, "_")}
+	// the gold is optional
+	for i, ptr := range []*int{&_m.Experience, &_m.Strength, &_m.Agility, &_m.Wisdom, &_m.Constitution, &_m.Gold} {
+		if i < len(numbers) {
+			*ptr = numbers[i]
+		}
+	}
+	return _m, true
+}
 
+// splitOutsideQuotes splits text on sep, except in "quoted texts"
+func splitOutsideQuotes(text string, sep rune) []string {
+	parts := []string{}
+	current := strings.Builder{}
+	inQuote := false
+
+	for _, r := range text {
+		if r == '"' {
+			inQuote = !inQuote
+		}
+		if r == sep && !inQuote {
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	return append(parts, current.String())
+}
+
+func (b *Bot) bestiaryCmd(req *Request) _Response {
+	if req.args.Has("monster") {
+		template, err := b.bestiary.Get(req.args.String("monster"))
+		if err != nil {
+			return simpleErr(fmt.Errorf("cannot read bestiary: %w", err), req.T("bestiary.unknown"))
 		}
-		*ptr = value
+		return simpleResponse(writeTemplate(req.tr, &template))
 	}
-	_m.CurrentHp = _m.GetMaxHP()
 
-	if err := b.db.SpawnMonster(_m); err != nil {
-		return simpleErr(fmt.Errorf("spawning monster: %w", err), "Error spawning monster")
+	templates, err := b.bestiary.List()
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot read bestiary: %w", err), req.T("bestiary.error"))
+	}
+
+	if len(templates) == 0 {
+		return simpleResponse(req.T("bestiary.empty"))
+	}
+
+	list := req.T("bestiary.title") + "\n"
+	for i := range templates {
+		t := &templates[i]
+		list += "- `" + t.Key + "` " + t.Name + " (" + strconv.Itoa(t.Experience) + " XP)"
+		if t.Description != "" {
+			list += ": " + t.Description
+		}
+		list += "\n"
+	}
+	return simpleResponse(list)
+}
+
+func writeTemplate(tr i18n.Translator, t *bestiary.Template) string {
+	str := "**" + t.Name + "** (`" + t.Key + "`)\n"
+	if t.Description != "" {
+		str += t.Description + "\n"
+	}
+	str += tr.T("bestiary.stats", i18n.Vars{
+		"XP":   t.Experience,
+		"Gold": t.Gold,
+		"Str":  t.Strength,
+		"Agi":  t.Agility,
+		"Wis":  t.Wisdom,
+		"Con":  t.Constitution,
+	}) + "\n"
+
+	if len(t.Abilities) > 0 {
+		str += tr.T("bestiary.abilities", i18n.Vars{"Abilities": strings.Join(t.Abilities, ", ")}) + "\n"
+	}
+
+	for _, loot := range t.Loot {
+		str += tr.T("bestiary.loot", i18n.Vars{
+			"Quantity": loot.Quantity,
+			"Item":     loot.Item,
+			"Chance":   strconv.FormatFloat(loot.Chance*100, 'f', -1, 64),
+		}) + "\n"
+	}
+	return str
+}
+
+func (b *Bot) spawnMonsters(req *Request, monsters []db.Monster) _Response {
+	tx := b.db.Begin()
+	defer tx.Rollback()
+
+	ids := []string{}
+	for i := range monsters {
+		monsters[i].CampaignID = req.Campaign.ID
+		monsters[i].Seed = b.dice.NewSeed()
+
+		if err := tx.SpawnMonster(&monsters[i]); err != nil {
+			return simpleErr(fmt.Errorf("spawning monster: %w", err), req.T("spawn.error"))
+		}
+		ids = append(ids, "#"+strconv.FormatUint(uint64(monsters[i].ID), 10))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return simpleErr(fmt.Errorf("spawning monster: %w", err), req.T("spawn.error"))
+	}
+
+	return simpleResponse(req.N("spawn.done", len(monsters), i18n.Vars{"IDs": strings.Join(ids, ", ")}))
+}
+
+func (b *Bot) reviveCmd(req *Request) _Response {
+	c, err := b.healCharacter(req.Campaign.ID, req.args.User("@player"), true)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot revive: %w", err), req.T("revive.error"))
+	}
+
+	return simpleResponse(req.T("revive.done", i18n.Vars{
+		"Player": util.DiscordIDToText(c.UserID),
+		"HP":     c.CurrentHp,
+		"MaxHP":  c.GetMaxHP(b.progression),
+	}))
+}
+
+// stockCmd puts an item on sale: !stock <item> <price> [stock], the stock is unlimited by default
+func (b *Bot) stockCmd(req *Request) _Response {
+	price, stock := req.args.Int("price"), req.args.IntOr("stock", db.UnlimitedStock)
+
+	name := req.args.String("item")
+	item, err := b.items.Get(name)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot stock: %w", err), req.T("item.unknown", i18n.Vars{"Item": name}))
+	}
+
+	if err := b.db.StockItem(req.Campaign.ID, item.Key, price, stock); err != nil {
+		return simpleErr(fmt.Errorf("cannot stock: %w", err), req.T("stock.error"))
+	}
+
+	vars := i18n.Vars{"Item": item.Name, "Price": price, "Stock": stock}
+	if stock == db.UnlimitedStock {
+		return simpleResponse(req.T("stock.done", vars))
+	}
+	return simpleResponse(req.T("stock.done_limited", vars))
+}
+
+func (b *Bot) unstockCmd(req *Request) _Response {
+	name := req.args.String("item")
+	item, err := b.items.Get(name)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot unstock: %w", err), req.T("item.unknown", i18n.Vars{"Item": name}))
+	}
+
+	if err := b.db.UnstockItem(req.Campaign.ID, item.Key); err != nil {
+		if errors.Is(err, db.ErrNotForSale) {
+			return simpleErr(err, req.T("unstock.not_for_sale", i18n.Vars{"Item": item.Name}))
+		}
+		return simpleErr(fmt.Errorf("cannot unstock: %w", err), req.T("unstock.error"))
+	}
+
+	return simpleResponse(req.T("unstock.done", i18n.Vars{"Item": item.Name}))
+}
+
+func (b *Bot) replayCmd(req *Request) _Response {
+	var (
+		monster db.Monster
+		err     error
+	)
+
+	if req.args.Has("monster id") {
+		monster, err = b.db.FetchMonster(req.Campaign.ID, uint(req.args.Int("monster id")))
+	} else {
+		monster, err = b.db.FetchLastDefeatedMonster(req.Campaign.ID)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return simpleResponse(req.T("replay.none"))
+	}
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch monster: %w", err), req.T("replay.error"))
+	}
+
+	rolls, err := b.db.FetchRolls(monster.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch rolls: %w", err), req.T("replay.error"))
+	}
+
+	participants, err := b.db.FetchParticipants(&monster)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch participants: %w", err), req.T("replay.error"))
 	}
 
-	return simpleResponse("Monster spawned")
+	return simpleResponse(writeReplay(req.tr, &monster, rolls, participants))
 }
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"testing"
+)
+
+func TestJoinAndCharacterSheet(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(10, "!character"), "rejoindre l'aventure en tapant !join_adventure")
+
+	expectContains(t, h.sayOne(10, "!join_adventure"), "<@10> a rejoint l'aventure !")
+	sheet := h.sayOne(10, "!character")
+	expectContains(t, sheet, "<@10> (Combattant) - 12 / 12 HP")
+	expectContains(t, sheet, "Il vous reste 5 points à répartir.")
+
+	expectContains(t, h.sayOne(10, "!characters"), "<@10> (niv. 1)")
+}
+
+func TestUpStats(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+
+	cases := []struct {
+		content string
+		answer  string
+	}{
+		{"!str abc", "Mauvaise syntaxe (points doit être un nombre), essayez `!str 1`"},
+		{"!agi -2", "points doit être au moins 1"},
+		{"!con 6", "Répartition impossible : il ne vous reste que 5 points."},
+		{"!str 2", "Répartition effectuée !"},
+		{"!con 3", "Répartition effectuée !"},
+		{"!wis 1", "Répartition impossible : il ne vous reste que 0 point."},
+	}
+	for _, c := range cases {
+		expectContains(t, h.sayOne(10, c.content), c.answer)
+	}
+
+	c := h.character(10)
+	if c.Strength != 3 || c.Constitution != 4 || c.Wisdom != 1 || c.SkillPoints != 0 {
+		t.Errorf("unexpected stats %+v", c)
+	}
+	if c.GetMaxHP(h.bot.progression) != 15 {
+		t.Errorf("expected 15 max HP, got %d", c.GetMaxHP(h.bot.progression))
+	}
+}
+
+const goblinTemplate = `{
+  "Name": "Goblin",
+  "Description": "Small and nasty.",
+  "Experience": 40,
+  "Gold": 15,
+  "Strength": 3,
+  "Agility": 2,
+  "Wisdom": 1,
+  "Constitution": 5,
+  "Loot": [{"Item": "dagger", "Chance": 0.3, "Quantity": 1}],
+  "Abilities": ["Low blow"]
+}`
+
+func TestSpawnFromBestiary(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(testGameMaster, "!bestiary"), "The bestiary is empty")
+	expectContains(t, h.sayOne(testGameMaster, "!spawn goblin"), "Unknown monster, see !bestiary")
+
+	h.addTemplate("goblin", goblinTemplate)
+
+	expectContains(t, h.sayOne(testGameMaster, "!bestiary"), "- `goblin` Goblin (40 XP): Small and nasty.")
+	details := h.sayOne(testGameMaster, "!bestiary Goblin")
+	expectContains(t, details, "40 XP, 15 gold - Str 3, Agi 2, Wis 1, Con 5")
+	expectContains(t, details, "Abilities: Low blow")
+	expectContains(t, details, "Loot: 1x dagger (30%)")
+
+	expectContains(t, h.sayOne(testGameMaster, "!spawn goblin x0"), "Bad count")
+	expectContains(t, h.sayOne(testGameMaster, "!spawn goblin x3; Rat_10_1_1_1_1"), "4 monsters spawned (#1, #2, #3, #4)")
+
+	watch := h.sayOne(10, "!watch")
+	expectContains(t, watch, "1. Goblin 1 - 15 / 15 HP")
+	expectContains(t, watch, "3. Goblin 3 - 15 / 15 HP")
+	expectContains(t, watch, "4. Rat - 11 / 11 HP")
+
+	m, err := h.store.FetchMonster(h.campaign(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Template != "goblin" || m.Experience != 40 || m.Gold != 15 || m.Strength != 3 {
+		t.Errorf("unexpected monster %+v", m)
+	}
+}
+
+func TestSpawnInvalidStats(t *testing.T) {
+	h := newHarness(t)
+
+	// negative stats would break the dice of the monster
+	expectContains(t, h.sayOne(testGameMaster, "!spawn Rat_10_1_-1_1_50"), "Invalid stats")
+	expectContains(t, h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_-10"), "Invalid stats")
+	expectContains(t, h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_-9"), "Monster spawned")
+}
+
+func TestRollDieWithoutSides(t *testing.T) {
+	for _, sides := range []int{0, -3} {
+		if r := rollDie(testSeed, 0, sides); r != 0 {
+			t.Errorf("rollDie with %d sides = %d, want 0", sides, r)
+		}
+	}
+}
+
+func TestSpawnCustomMonsters(t *testing.T) {
+	h := newHarness(t)
+	h.addTemplate("goblin", goblinTemplate)
+
+	expectContains(t, h.sayOne(testGameMaster, `!spawn "Rat_king" xp=10 str=1 con=5; goblin count=2 con=10; Giant_rat_5_1_1_1_1_3`),
+		"4 monsters spawned (#1, #2, #3, #4)")
+
+	watch := h.sayOne(10, "!watch")
+	expectContains(t, watch, "1. Rat_king - 15 / 15 HP")
+	expectContains(t, watch, "3. Goblin 2 - 20 / 20 HP")
+	expectContains(t, watch, "4. Giant_rat - 11 / 11 HP")
+
+	m, err := h.store.FetchMonster(h.campaign(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Experience != 5 || m.Gold != 3 {
+		t.Errorf("unexpected monster %+v", m)
+	}
+
+	expectContains(t, h.sayOne(testGameMaster, "!spawn Rat king"), "Unknown monster")
+	expectContains(t, h.sayOne(testGameMaster, `!spawn "Rat king" xp=ten`), "(xp must be a number)")
+	expectContains(t, h.sayOne(testGameMaster, `!spawn "Rat king" agi=-1`), "(agi must be at least 0)")
+	expectContains(t, h.sayOne(testGameMaster, `!spawn "Rat king" con=-10`), "(con must be at least -9)")
+}
@@ -0,0 +1,490 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+)
+
+// _ArgKind tells how an argument is read
+type _ArgKind int
+
+const (
+	// argWord is a single word
+	argWord _ArgKind = iota
+	// argText is the rest of the message, several words
+	argText
+	argInt
+	// argMention is a user mention
+	argMention
+	// argEnum is one of the Choices, case insensitive
+	argEnum
+	// argFlag is an option without value, written --name
+	argFlag
+)
+
+// _Arg describes an argument, or a key=value option, of a command.
+// The arg.<name> message of the catalog labels it in !help.
+type _Arg struct {
+	Name     string
+	Kind     _ArgKind
+	Optional bool
+	// Range bounds an argInt
+	Range   *_Range
+	Choices []string
+}
+
+// _Command declares a chat command: its handler, and what !help tells about it.
+// The command.<name> message of the catalog describes it.
+type _Command struct {
+	Name    string
+	Aliases []string
+	Args    []_Arg
+	// Options are optional key=value arguments, or --flags
+	Options []_Arg
+	// Permission is the role required to run the command
+	Permission db.Role
+	// Channels are where the command can be played during an adventure, anywhere by default
+	Channels channelScope
+	// Usage is an example of the command
+	Usage string
+	// Ephemeral slash command answers are only shown to the author
+	Ephemeral bool
+	// TextOnly commands are not published as slash commands
+	TextOnly bool
+	handler  _Handler
+}
+
+var (
+	// commands are listed in the !help order
+	commands []*_Command //nolint:gochecknoglobals
+	// cmd router, by name and alias
+	router map[string]*_Command //nolint:gochecknoglobals
+)
+
+// the registry is built at init, as !help reads it
+func init() { //nolint:gochecknoinits
+	commands = registry()
+	router = newRouter(commands)
+}
+
+func registry() []*_Command {
+	return []*_Command{
+		{
+			Name: "help", Aliases: []string{"aide"},
+			Args:      []_Arg{{Name: "command", Optional: true}},
+			Usage:     "!help hit",
+			Ephemeral: true,
+			handler:   (*Bot).helpCmd,
+		},
+		{
+			Name: "join_adventure", Aliases: []string{"join"},
+			Args:     []_Arg{{Name: "class", Optional: true}},
+			Channels: inAdventureChannel,
+			Usage:    "!join_adventure mage",
+			handler:  (*Bot).joinAdventure,
+		},
+		{
+			Name:     "class",
+			Args:     []_Arg{{Name: "class"}},
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!class soigneur",
+			handler:  (*Bot).classCmd,
+		},
+		{
+			Name: "character", Aliases: []string{"char", "fiche"},
+			Channels:  inAdventureOrDirectMessages,
+			Usage:     "!character",
+			Ephemeral: true,
+			handler:   (*Bot).characterCmd,
+		},
+		{
+			Name:      "xp",
+			Channels:  inAdventureOrDirectMessages,
+			Usage:     "!xp",
+			Ephemeral: true,
+			handler:   (*Bot).xpCmd,
+		},
+		{
+			Name:     "characters",
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!characters",
+			handler:  (*Bot).charactersCmd,
+		},
+		{
+			Name:     "str",
+			Args:     []_Arg{{Name: "points", Kind: argInt, Range: atLeast(1)}},
+			Channels: inAdventureOrDirectMessages,
+			Options:  statOptions,
+			Usage:    "!str 1",
+			TextOnly: true,
+			handler:  handleUpStatsFunctor("strength"),
+		},
+		{
+			Name:     "agi",
+			Args:     []_Arg{{Name: "points", Kind: argInt, Range: atLeast(1)}},
+			Channels: inAdventureOrDirectMessages,
+			Options:  statOptions,
+			Usage:    "!agi 1",
+			TextOnly: true,
+			handler:  handleUpStatsFunctor("agility"),
+		},
+		{
+			Name:     "wis",
+			Args:     []_Arg{{Name: "points", Kind: argInt, Range: atLeast(1)}},
+			Channels: inAdventureOrDirectMessages,
+			Options:  statOptions,
+			Usage:    "!wis 1",
+			TextOnly: true,
+			handler:  handleUpStatsFunctor("wisdom"),
+		},
+		{
+			Name:     "con",
+			Args:     []_Arg{{Name: "points", Kind: argInt, Range: atLeast(1)}},
+			Channels: inAdventureOrDirectMessages,
+			Options:  statOptions,
+			Usage:    "!con 1",
+			TextOnly: true,
+			handler:  handleUpStatsFunctor("constitution"),
+		},
+		{
+			Name: "stats",
+			Args: []_Arg{
+				{Name: "stat", Kind: argEnum, Choices: []string{"str", "agi", "wis", "con"}},
+				{Name: "points", Kind: argInt, Range: atLeast(1)},
+			},
+			Options:  statOptions,
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!stats str 2",
+			handler:  (*Bot).statsCmd,
+		},
+		{
+			Name:      "respec",
+			Channels:  inAdventureOrDirectMessages,
+			Usage:     "!respec",
+			Ephemeral: true,
+			handler:   (*Bot).respecCmd,
+		},
+		{
+			Name:     "watch",
+			Channels: inAdventureChannel,
+			Usage:    "!watch",
+			handler:  (*Bot).watchCmd,
+		},
+		{
+			Name: "hit", Aliases: []string{"attack"},
+			Args:     []_Arg{{Name: "target", Kind: argText, Optional: true}},
+			Channels: inAdventureChannel,
+			Usage:    "!hit 2",
+			handler:  (*Bot).hitCmd,
+		},
+		{
+			Name:     "heal",
+			Args:     []_Arg{{Name: "@player", Kind: argMention}},
+			Channels: inAdventureChannel,
+			Usage:    "!heal @joueur",
+			handler:  (*Bot).healCmd,
+		},
+		{
+			Name:     "rest",
+			Channels: inAdventureChannel,
+			Usage:    "!rest",
+			handler:  (*Bot).restCmd,
+		},
+		{
+			Name:      "battle_log",
+			Args:      []_Arg{{Name: "monster id", Kind: argInt, Optional: true, Range: atLeast(1)}},
+			Channels:  inAdventureOrDirectMessages,
+			Usage:     "!battle_log 12",
+			Ephemeral: true,
+			handler:   (*Bot).battleLogCmd,
+		},
+		{
+			Name: "inventory", Aliases: []string{"inv"},
+			Channels:  inAdventureOrDirectMessages,
+			Usage:     "!inventory",
+			Ephemeral: true,
+			handler:   (*Bot).inventoryCmd,
+		},
+		{
+			Name:     "equip",
+			Args:     []_Arg{{Name: "item", Kind: argText}},
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!equip dagger",
+			handler:  (*Bot).equipCmd,
+		},
+		{
+			Name:     "unequip",
+			Args:     []_Arg{{Name: "item", Kind: argText}},
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!unequip dagger",
+			handler:  (*Bot).unequipCmd,
+		},
+		{
+			Name:     "use",
+			Args:     []_Arg{{Name: "item", Kind: argText}},
+			Channels: inAdventureChannel,
+			Usage:    "!use potion",
+			handler:  (*Bot).useCmd,
+		},
+		{
+			Name:     "drop",
+			Args:     []_Arg{{Name: "item", Kind: argText}, {Name: "quantity", Kind: argInt, Optional: true, Range: atLeast(1)}},
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!drop potion 2",
+			handler:  (*Bot).dropCmd,
+		},
+		{
+			Name:     "shop",
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!shop",
+			handler:  (*Bot).shopCmd,
+		},
+		{
+			Name:     "buy",
+			Args:     []_Arg{{Name: "item", Kind: argText}, {Name: "quantity", Kind: argInt, Optional: true, Range: atLeast(1)}},
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!buy potion 2",
+			handler:  (*Bot).buyCmd,
+		},
+		{
+			Name:     "sell",
+			Args:     []_Arg{{Name: "item", Kind: argText}, {Name: "quantity", Kind: argInt, Optional: true, Range: atLeast(1)}},
+			Channels: inAdventureOrDirectMessages,
+			Usage:    "!sell dagger",
+			handler:  (*Bot).sellCmd,
+		},
+		{
+			Name: "adventure_status", Aliases: []string{"status"},
+			Usage:     "!adventure_status",
+			Ephemeral: true,
+			handler:   (*Bot).adventureStatusCmd,
+		},
+		// moderator cmd
+		{
+			Name:       "shout",
+			Args:       []_Arg{{Name: "message", Kind: argText}},
+			Permission: db.RoleModerator,
+			Usage:      "!shout A dragon approaches!",
+			handler:    (*Bot).shoutCmd,
+		},
+		{
+			Name:       "revive",
+			Args:       []_Arg{{Name: "@player", Kind: argMention}},
+			Permission: db.RoleModerator,
+			Usage:      "!revive @player",
+			handler:    (*Bot).reviveCmd,
+		},
+		{
+			Name:       "replay",
+			Args:       []_Arg{{Name: "monster id", Kind: argInt, Optional: true, Range: atLeast(1)}},
+			Permission: db.RoleModerator,
+			Usage:      "!replay 12",
+			handler:    (*Bot).replayCmd,
+		},
+		{
+			Name:       "bestiary",
+			Args:       []_Arg{{Name: "monster", Optional: true}},
+			Permission: db.RoleModerator,
+			Usage:      "!bestiary goblin",
+			Ephemeral:  true,
+			handler:    (*Bot).bestiaryCmd,
+		},
+		// game master cmd
+		{
+			Name:       "start_adventure",
+			Permission: db.RoleGameMaster,
+			Usage:      "!start_adventure",
+			handler:    (*Bot).startAdventureCmd,
+		},
+		{
+			Name:       "end_adventure",
+			Permission: db.RoleGameMaster,
+			Usage:      "!end_adventure",
+			handler:    (*Bot).endAdventureCmd,
+		},
+		{
+			Name:       "spawn",
+			Args:       []_Arg{{Name: "monsters", Kind: argText}},
+			Permission: db.RoleGameMaster,
+			Usage:      "!spawn goblin x3 con=8; \"Rat king\" xp=100 str=5 agi=2 wis=2 con=20",
+			handler:    (*Bot).spawnCmd,
+		},
+		{
+			Name: "stock",
+			Args: []_Arg{
+				{Name: "item", Kind: argText},
+				{Name: "price", Kind: argInt, Range: atLeast(0)},
+				{Name: "stock", Kind: argInt, Optional: true, Range: atLeast(0)},
+			},
+			Permission: db.RoleGameMaster,
+			Usage:      "!stock potion 5 10",
+			handler:    (*Bot).stockCmd,
+		},
+		{
+			Name:       "unstock",
+			Args:       []_Arg{{Name: "item", Kind: argText}},
+			Permission: db.RoleGameMaster,
+			Usage:      "!unstock potion",
+			handler:    (*Bot).unstockCmd,
+		},
+		{
+			Name: "gm",
+			Args: []_Arg{
+				{Name: "action", Kind: argEnum, Choices: []string{"add", "remove", "list"}},
+				{Name: "@player|@role", Optional: true},
+				{Name: "gm|moderator", Optional: true},
+			},
+			Permission: db.RoleGameMaster,
+			Usage:      "!gm add @player moderator",
+			handler:    (*Bot).gmCmd,
+		},
+		{
+			Name:       "prefix",
+			Args:       []_Arg{{Name: "prefix", Optional: true}},
+			Permission: db.RoleGameMaster,
+			Usage:      "!prefix ?",
+			Ephemeral:  true,
+			handler:    (*Bot).prefixCmd,
+		},
+		{
+			Name:       "split",
+			Args:       []_Arg{{Name: "strategy", Kind: argEnum, Choices: db.SplitStrategies, Optional: true}},
+			Permission: db.RoleGameMaster,
+			Usage:      "!split proportional",
+			Ephemeral:  true,
+			handler:    (*Bot).splitCmd,
+		},
+		{
+			Name: "lang",
+			Args: []_Arg{
+				{Name: "locale", Optional: true},
+				{Name: "scope", Kind: argEnum, Choices: []string{"player", "campaign"}, Optional: true},
+			},
+			Usage:     "!lang en",
+			Ephemeral: true,
+			handler:   (*Bot).langCmd,
+		},
+	}
+}
+
+func newRouter(commands []*_Command) map[string]*_Command {
+	r := map[string]*_Command{}
+	for _, cmd := range commands {
+		if cmd.Permission == "" {
+			cmd.Permission = db.RolePlayer
+		}
+		if cmd.Channels == 0 {
+			cmd.Channels = anywhere
+		}
+
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			if _, ok := r[name]; ok {
+				panic("command declared twice: " + name)
+			}
+			r[name] = cmd
+		}
+	}
+	return r
+}
+
+// description tells what the command does, in the language of the translator
+func (cmd *_Command) description(tr i18n.Translator) string {
+	return tr.T("command." + cmd.Name)
+}
+
+// argLabel writes the name of an argument in the language of the translator, or as declared when it has no label
+func argLabel(tr i18n.Translator, name string) string {
+	if !tr.Has("arg." + name) {
+		return name
+	}
+	return tr.T("arg." + name)
+}
+
+// syntax writes the command with its arguments, optional ones in brackets
+func (cmd *_Command) syntax(tr i18n.Translator, prefix string) string {
+	str := prefix + cmd.Name
+	for _, arg := range cmd.Args {
+		name := argLabel(tr, arg.Name)
+		if arg.Kind == argEnum {
+			name = strings.Join(arg.Choices, "|")
+		}
+
+		if arg.Optional {
+			str += " [" + name + "]"
+		} else {
+			str += " <" + name + ">"
+		}
+	}
+	for _, option := range cmd.Options {
+		if option.Kind == argFlag {
+			str += " [--" + option.Name + "]"
+			continue
+		}
+		str += " [" + option.Name + "=…]"
+	}
+	return str
+}
+
+// usage writes the example of the command with the prefix of the campaign
+func (cmd *_Command) usage(prefix string) string {
+	return prefix + strings.TrimPrefix(cmd.Usage, db.DefaultPrefix)
+}
+
+// usageErr answers a bad syntax with the usage of the command, and the reason when the parser knows it
+func (cmd *_Command) usageErr(req *Request, err error) _Response {
+	prefix := req.Campaign.CommandPrefix()
+
+	reason := ""
+	var argErr *argError
+	if errors.As(err, &argErr) {
+		reason = " (" + argErr.describe(req.tr) + ")"
+	}
+
+	return simpleErr(err, req.T("usage.error", i18n.Vars{
+		"Reason": reason,
+		"Syntax": cmd.syntax(req.tr, prefix),
+		"Usage":  cmd.usage(prefix),
+	}))
+}
+
+// helpCmd lists the commands available to the author, or details one of them
+func (b *Bot) helpCmd(req *Request) _Response {
+	role, err := b.role(req)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch permissions: %w", err), req.T("help.error"))
+	}
+	prefix := req.Campaign.CommandPrefix()
+
+	if name := req.args.String("command"); name != "" {
+		cmd, ok := router[strings.TrimPrefix(strings.ToLower(name), prefix)]
+		if !ok || !role.Includes(cmd.Permission) {
+			return simpleErr(fmt.Errorf("help %q: %w", name, errIllegalArgument), req.T("help.unknown"))
+		}
+		return simpleResponse(writeCommandHelp(req.tr, cmd, prefix))
+	}
+
+	str := req.T("help.title") + "\n"
+	for _, cmd := range commands {
+		if role.Includes(cmd.Permission) {
+			str += req.T("help.entry", i18n.Vars{
+				"Syntax":      cmd.syntax(req.tr, prefix),
+				"Description": cmd.description(req.tr),
+			}) + "\n"
+		}
+	}
+	return simpleResponse(str + req.T("help.more", i18n.Vars{"Prefix": prefix}))
+}
+
+func writeCommandHelp(tr i18n.Translator, cmd *_Command, prefix string) string {
+	str := "`" + cmd.syntax(tr, prefix) + "`\n" + cmd.description(tr) + "\n"
+	if len(cmd.Aliases) > 0 {
+		str += tr.N("help.aliases", len(cmd.Aliases), i18n.Vars{
+			"Aliases": "`" + prefix + strings.Join(cmd.Aliases, "`, `"+prefix) + "`",
+		}) + "\n"
+	}
+	return str + tr.T("help.example", i18n.Vars{"Usage": cmd.usage(prefix)}) + "\n"
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryIsComplete(t *testing.T) {
+	for _, cmd := range commands {
+		if !strings.HasPrefix(cmd.Usage, "!"+cmd.Name) || cmd.handler == nil {
+			t.Errorf("incomplete command %+v", cmd)
+		}
+	}
+}
+
+func TestLocalesAreComplete(t *testing.T) {
+	messages := newHarness(t).bot.messages
+
+	want := messages.Keys(defaultLocale)
+	known := map[string]bool{}
+	for _, key := range want {
+		known[key] = true
+	}
+	for _, cmd := range commands {
+		for _, key := range append([]string{"command." + cmd.Name}, argKeys(cmd)...) {
+			if !known[key] {
+				t.Errorf("%s: missing %q", cmd.Name, key)
+			}
+		}
+	}
+
+	for _, locale := range messages.Locales() {
+		if got := messages.Keys(locale); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%s: the messages differ from %s", locale, defaultLocale)
+		}
+	}
+}
+
+func argKeys(cmd *_Command) []string {
+	keys := []string{}
+	for _, arg := range slashArgs(cmd) {
+		keys = append(keys, "arg."+arg.Name)
+	}
+	return keys
+}
+
+func TestHelp(t *testing.T) {
+	h := newHarness(t)
+
+	help := h.sayOne(10, "!help")
+	expectContains(t, help, "`!str <points> [--dry-run]` : Répartit des points en force.\n")
+	expectContains(t, help, "`!hit [cible]` : ")
+	if strings.Contains(help, "!spawn") {
+		t.Errorf("players cannot spawn: %q", help)
+	}
+	expectContains(t, h.sayOne(testGameMaster, "!aide"), "`!spawn <monsters>`: ")
+
+	expectContains(t, h.sayOne(10, "!help character"),
+		"`!character`\nAffiche la fiche de votre personnage.\nAlias : `!char`, `!fiche`\nExemple : `!character`\n")
+	expectContains(t, h.sayOne(10, "!help spawn"), "Commande inconnue")
+	expectContains(t, h.sayOne(testGameMaster, "!help !gm"), "`!gm <add|remove|list> [@player|@role] [gm|moderator]`")
+}
+
+func TestAliases(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(10, "!join"), "<@10> a rejoint l'aventure !")
+	expectContains(t, h.sayOne(10, "!fiche"), "<@10> (Combattant)")
+}
+
+func TestUsageErrors(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+
+	expectContains(t, h.sayOne(10, "!str"), "Mauvaise syntaxe (points manquant), essayez `!str 1`")
+	expectContains(t, h.sayOne(10, "!agi one"), "Mauvaise syntaxe (points doit être un nombre), essayez `!agi 1`")
+	expectContains(t, h.sayOne(10, "!con 1 2"), "Mauvaise syntaxe (trop d'arguments), essayez `!con 1`")
+	expectContains(t, h.sayOne(10, "!watch now"), "Mauvaise syntaxe (trop d'arguments), essayez `!watch`")
+	expectContains(t, h.sayOne(10, "!heal Bob"), "Mauvaise syntaxe (@joueur doit mentionner un joueur), essayez `!heal @joueur`")
+
+	expectContains(t, h.sayOne(testGameMaster, "!revive"),
+		"Bad arguments (missing @player). Syntax: `!revive <@player>`, for example `!revive @player`")
+	expectContains(t, h.sayOne(testGameMaster, "!replay last"), "Syntax: `!replay [monster id]`")
+	expectContains(t, h.sayOne(testGameMaster, "!gm promote <@10>"), "Syntax: `!gm <add|remove|list>")
+	expectContains(t, h.sayOne(testGameMaster, "!gm add"), "(@player|@role must mention a player)")
+	expectContains(t, h.sayOne(testGameMaster, "!stock sword -1"), "(price must be at least 0)")
+}
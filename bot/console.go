@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	consoleChannel = "console"
+	// consoleGuild hosts the campaign played from the console
+	consoleGuild = "console"
+)
+
+// consoleTransport prints responses on a writer
+type consoleTransport struct {
+	w io.Writer
+}
+
+func (t consoleTransport) Send(channelID string, message string) error {
+	_, err := fmt.Fprintf(t.w, "[%s] %s\n", channelID, message)
+	return err
+}
+
+// RunConsole drives the bot from a local REPL, without any Discord connection.
+// Lines are read as chat messages sent by authorID; `/as <id>` switches the current player.
+func (b *Bot) RunConsole(in io.Reader, out io.Writer, authorID uint) error {
+	t := consoleTransport{w: out}
+	scanner := bufio.NewScanner(in)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "/as ") {
+			id, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "/as ")), 10, 64)
+			if err != nil {
+				fmt.Fprintln(out, "usage: /as <user id>")
+				continue
+			}
+			authorID = uint(id)
+			continue
+		}
+
+		req := NewRequest(line, authorID, strconv.FormatUint(uint64(authorID), 10), consoleChannel)
+		req.GuildID = consoleGuild
+
+		b.Dispatch(t, req)
+	}
+
+	return scanner.Err()
+}
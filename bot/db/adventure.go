@@ -0,0 +1,46 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Adventure statuses
+const (
+	AdventureNotStarted = ""
+	AdventureRunning    = "running"
+	AdventureEnded      = "ended"
+)
+
+// Adventure holds the settings of the current adventure of a campaign
+type Adventure struct {
+	gorm.Model
+	CampaignID uint `gorm:"uniqueIndex"`
+	// ChannelID is where the adventure takes place
+	ChannelID string
+	StartedAt time.Time
+	// GameMaster is who started the adventure
+	GameMaster uint
+	Status     string
+}
+
+// IsRunning tells if the adventure is started and not ended yet
+func (a Adventure) IsRunning() bool {
+	return a.Status == AdventureRunning
+}
+
+// FetchAdventure returns the adventure settings of a campaign, not started if there is none yet
+func (db *DB) FetchAdventure(campaignID uint) (Adventure, error) {
+	a := Adventure{}
+	err := db.Where("campaign_id = ?", campaignID).First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Adventure{CampaignID: campaignID}, nil
+	}
+	return a, err
+}
+
+func (db *DB) SaveAdventure(a *Adventure) error {
+	return db.Save(a).Error
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// Actions of the battle log
+const (
+	ActionStrike  = "strike"
+	ActionSpell   = "spell"
+	ActionStaff   = "staff"
+	ActionRiposte = "riposte"
+	ActionHeal    = "heal"
+)
+
+// BattleEvent is an action of a fight against a monster, kept for the battle log
+type BattleEvent struct {
+	gorm.Model
+	MonsterID uint `gorm:"index"`
+	Action    string
+	// ActorID is the acting character and TargetID the character hit or healed, 0 stands for the monster
+	ActorID  uint
+	TargetID uint
+	// Roll is the die drawn for the action, every draw is kept as a BattleRoll
+	Roll   int
+	Damage int
+	Heal   int
+	// HP is what the target has left after the action
+	HP int
+}
+
+// RecordEvent saves an action of the battle against m
+func (db *DB) RecordEvent(m *Monster, e BattleEvent) error {
+	e.MonsterID = m.ID
+	return db.Create(&e).Error
+}
+
+// FetchEvents returns the battle log of a monster, in order
+func (db *DB) FetchEvents(monsterID uint) (events []BattleEvent, e error) {
+	e = db.Where("monster_id = ?", monsterID).Order("id").Find(&events).Error
+	return
+}
+
+// FetchFoughtMonsters returns the last monsters fought in the campaign, dead or alive, the latest first
+func (db *DB) FetchFoughtMonsters(campaignID uint, limit int) (monsters []Monster, e error) {
+	fought := db.Session(&gorm.Session{NewDB: true}).Model(&BattleEvent{}).Select("monster_id")
+	e = db.Where("campaign_id = ? AND id IN (?)", campaignID, fought).
+		Order("updated_at desc").Order("id desc").Limit(limit).Find(&monsters).Error
+	return
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// BattleRoll is a dice roll made during a fight, kept to audit the battle
+type BattleRoll struct {
+	gorm.Model
+	MonsterID   uint `gorm:"index"`
+	RollIndex   int
+	CharacterID uint
+	Sides       int
+	Result      int
+}
+
+// RecordRoll saves the next roll of the battle against m
+func (db *DB) RecordRoll(m *Monster, r BattleRoll) error {
+	r.MonsterID = m.ID
+	r.RollIndex = m.Rolls
+	if err := db.Create(&r).Error; err != nil {
+		return err
+	}
+
+	m.Rolls++
+	return db.Model(&Monster{Model: gorm.Model{ID: m.ID}}).Update("rolls", m.Rolls).Error
+}
+
+func (db *DB) FetchRolls(monsterID uint) (rolls []BattleRoll, e error) {
+	e = db.Where("monster_id = ?", monsterID).Order("roll_index").Find(&rolls).Error
+	return
+}
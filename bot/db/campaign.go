@@ -0,0 +1,156 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPrefix starts the commands of a campaign without a custom prefix
+const DefaultPrefix = "!"
+
+// Strategies sharing the rewards of a fight between its participants
+const (
+	// SplitEven gives the same share to everyone
+	SplitEven = "even"
+	// SplitProportional shares along the damage dealt
+	SplitProportional = "proportional"
+	// SplitLastHit gives a bonus to the one who struck the last blow, then shares evenly
+	SplitLastHit = "last_hit"
+)
+
+// SplitStrategies lists the ways to share the rewards of a fight
+var SplitStrategies = []string{SplitEven, SplitProportional, SplitLastHit} //nolint:gochecknoglobals
+
+// Campaign is an independent game, hosted on a Discord server (guild).
+// Characters, monsters, the shop and the adventure belong to a campaign.
+type Campaign struct {
+	gorm.Model
+	// GuildID identifies the Discord server, or the transport hosting the game
+	GuildID string `gorm:"uniqueIndex"`
+	// Prefix starts the commands, DefaultPrefix when empty
+	Prefix string
+	// Locale is the language of the campaign, the default locale of the bot when empty
+	Locale string
+	// RewardSplit shares the experience, the gold and the loot of the fights, SplitEven when empty
+	RewardSplit string
+}
+
+// CommandPrefix returns the prefix starting the commands of the campaign
+func (c *Campaign) CommandPrefix() string {
+	if c.Prefix == "" {
+		return DefaultPrefix
+	}
+	return c.Prefix
+}
+
+// Split returns the strategy sharing the rewards of the fights of the campaign
+func (c *Campaign) Split() string {
+	if c.RewardSplit == "" {
+		return SplitEven
+	}
+	return c.RewardSplit
+}
+
+// campaignTables are the tables scoped by a campaign_id column
+var campaignTables = []interface{}{&Character{}, &Monster{}, &ShopItem{}} //nolint:gochecknoglobals
+
+// FetchCampaign returns the campaign of a guild
+func (db *DB) FetchCampaign(guildID string) (c Campaign, e error) {
+	e = db.Where("guild_id = ?", guildID).First(&c).Error
+	return
+}
+
+// FetchOrCreateCampaign returns the campaign of a guild. A new campaign is run by gameMaster, if any.
+func (db *DB) FetchOrCreateCampaign(guildID string, gameMaster uint) (Campaign, error) {
+	tx := db.begin()
+	defer tx.Rollback()
+
+	c := Campaign{}
+	err := tx.Where("guild_id = ?", guildID).First(&c).Error
+	if err == nil {
+		return c, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c, err
+	}
+
+	c = Campaign{GuildID: guildID}
+	if err := tx.Create(&c).Error; err != nil {
+		return c, err
+	}
+
+	if gameMaster != 0 {
+		if err := tx.Grant(&Permission{CampaignID: c.ID, UserID: gameMaster, Role: RoleGameMaster}); err != nil {
+			return c, err
+		}
+	}
+
+	return c, tx.Commit()
+}
+
+// AdoptLegacyGame moves the game played before campaigns into the campaign of the guild
+// owning its adventure channel, where the adventure runs unless the campaign already has one
+func (db *DB) AdoptLegacyGame(guildID string, adventureChannel string, gameMaster uint, now time.Time) error {
+	c, err := db.FetchOrCreateCampaign(guildID, gameMaster)
+	if err != nil {
+		return err
+	}
+
+	tx := db.begin()
+	defer tx.Rollback()
+
+	a, err := tx.FetchAdventure(c.ID)
+	if err != nil {
+		return err
+	}
+	if a.ChannelID == "" {
+		a.ChannelID = adventureChannel
+		a.StartedAt = now
+		a.GameMaster = gameMaster
+		a.Status = AdventureRunning
+		if err := tx.SaveAdventure(&a); err != nil {
+			return err
+		}
+	}
+	if err := tx.adoptLegacyData(c.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) adoptLegacyData(campaignID uint) error {
+	if err := db.Model(&Character{}).
+		Where("campaign_id = 0 OR campaign_id IS NULL").
+		Updates(map[string]interface{}{"campaign_id": campaignID, "user_id": gorm.Expr("id")}).Error; err != nil {
+		return err
+	}
+
+	for _, table := range campaignTables[1:] {
+		if err := db.Model(table).
+			Where("campaign_id = 0 OR campaign_id IS NULL").
+			Update("campaign_id", campaignID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FetchLatestCampaign returns the campaign where the user played last
+func (db *DB) FetchLatestCampaign(userID uint) (Campaign, error) {
+	c := Campaign{}
+
+	character := Character{}
+	if err := db.Where("user_id = ?", userID).Order("updated_at desc, id desc").First(&character).Error; err != nil {
+		return c, err
+	}
+
+	err := db.First(&c, character.CampaignID).Error
+	return c, err
+}
+
+func (db *DB) SaveCampaign(c *Campaign) error {
+	return db.Save(c).Error
+}
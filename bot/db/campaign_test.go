@@ -0,0 +1,121 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+)
+
+func TestCampaignsAreIndependent(t *testing.T) {
+	store := newTestStore(t)
+
+	first, err := store.FetchOrCreateCampaign("guild-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.FetchOrCreateCampaign("guild-2", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("unexpected campaigns %+v and %+v", first, second)
+	}
+
+	again, err := store.FetchOrCreateCampaign("guild-1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("expected the existing campaign, got %+v", again)
+	}
+
+	permissions, err := store.FetchPermissions(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(permissions) != 1 || permissions[0].UserID != 2 || permissions[0].Role != RoleGameMaster {
+		t.Errorf("expected the second campaign to be run by its creator, got %+v", permissions)
+	}
+
+	// the same player has one character per campaign
+	if err := store.CreateCharacter(progression.Default(), first.ID, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateCharacter(progression.Default(), first.ID, 10, ""); !errors.Is(err, errCharacterAlreadyExists) {
+		t.Errorf("expected a duplicate character to be refused, got %v", err)
+	}
+	if err := store.CreateCharacter(progression.Default(), second.ID, 10, ClassMage); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SpawnMonster(&Monster{CampaignID: first.ID, Name: "Rat", CurrentHp: 11}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.FetchMonsterInfo(second.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected no monster in the second campaign, got %v", err)
+	}
+
+	c, err := store.FetchCharacterInfo(second.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Class != ClassMage {
+		t.Errorf("expected the mage of the second campaign, got %+v", c)
+	}
+
+	latest, err := store.FetchLatestCampaign(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.ID != second.ID {
+		t.Errorf("expected the last campaign played, got %+v", latest)
+	}
+}
+
+func TestLegacyGameIsAdoptedByItsGuild(t *testing.T) {
+	store := newTestStore(t)
+
+	// rows created before campaigns: the character ID was the discord ID
+	legacy := NewCharacter(progression.Default(), "")
+	legacy.ID = 10
+	if err := store.Create(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SpawnMonster(&Monster{Name: "Rat", CurrentHp: 11}); err != nil {
+		t.Fatal(err)
+	}
+
+	// another guild playing first does not take the legacy game
+	other, err := store.FetchOrCreateCampaign("guild-2", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.FetchMonsterInfo(other.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected no monster in the other guild, got %v", err)
+	}
+
+	if err := store.AdoptLegacyGame("guild-1", "adventure", 1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	campaign, err := store.FetchOrCreateCampaign("guild-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adventure, err := store.FetchAdventure(campaign.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adventure.ChannelID != "adventure" || !adventure.IsRunning() {
+		t.Errorf("expected the legacy adventure to run, got %+v", adventure)
+	}
+	if _, err := store.FetchCharacterInfo(campaign.ID, 10); err != nil {
+		t.Errorf("expected the legacy character to be adopted, got %v", err)
+	}
+	if _, err := store.FetchMonsterInfo(campaign.ID); err != nil {
+		t.Errorf("expected the legacy monster to be adopted, got %v", err)
+	}
+}
@@ -2,17 +2,23 @@ package db
 
 import (
 	"errors"
-	"strconv"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 
-	"github.com/vincent-heng/discord-airpgbot/bot/util"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
 )
 
-// Character represents a character in DB, played by a discord user
+// Character represents a character in DB, played by a discord user in a campaign
 type Character struct {
 	gorm.Model
-	Class        string
+	CampaignID uint `gorm:"uniqueIndex:idx_character_campaign_user_unique"`
+	// UserID is the discord ID of the player
+	UserID uint `gorm:"uniqueIndex:idx_character_campaign_user_unique"`
+	Class  string
+	// ClassChosen is false until the player picks a class, once
+	ClassChosen  bool
 	Experience   int
 	Level        int
 	Strength     int
@@ -22,112 +28,209 @@ type Character struct {
 	SkillPoints  int
 	CurrentHp    int
 	Stamina      int
+	Gold         int
+	// RegeneratedAt is the last time stamina and HP regeneration was applied
+	RegeneratedAt time.Time
+	// RespecAt is the last time the skill points were refunded
+	RespecAt time.Time
 }
 
-func (c Character) String() string {
-	str := util.DiscordIDToText(c.ID) + " (" + c.Class + ") - " +
-		strconv.Itoa(c.CurrentHp) + " / " + strconv.Itoa(c.GetMaxHP()) + " HP\n" +
-		"Endurance : " + strconv.Itoa(c.Stamina) + " / 100\n" +
-		"Niveau " + strconv.Itoa(c.Level) + " (" + strconv.Itoa(c.Experience) + " XP)\n" +
-		"Force : " + strconv.Itoa(c.Strength) + "\n" +
-		"Agilité : " + strconv.Itoa(c.Agility) + "\n" +
-		"Sagesse : " + strconv.Itoa(c.Wisdom) + "\n" +
-		"Constitution : " + strconv.Itoa(c.Constitution) + "\n"
-
-	if c.SkillPoints > 0 {
-		plural := ""
-		if c.SkillPoints > 1 {
-			plural = "s"
-		}
-		str += "\nIl vous reste " + strconv.Itoa(c.SkillPoints) + " point" + plural + " à répartir.\n"
+const (
+	// BaseStat is every stat of a new character, before spending skill points
+	BaseStat   = 1
+	MaxStamina = 100
+	// every RegenInterval, a character regains StaminaRegen stamina and HPRegen HP
+	RegenInterval = time.Minute
+	StaminaRegen  = 2
+	HPRegen       = 1
+)
+
+// RegenColumns are the columns changed by Regenerate, and by the damage and the heals
+var RegenColumns = []string{"current_hp", "stamina", "regenerated_at"} //nolint:gochecknoglobals
+
+// StatColumns are the columns changed by AddStat and Respec
+var StatColumns = []string{"strength", "agility", "wisdom", "constitution", "skill_points"} //nolint:gochecknoglobals
+
+// GetMaxHP returns the max HP of the character along the progression curve
+func (c Character) GetMaxHP(curve *progression.Curve) int {
+	return curve.MaxHP(c.Constitution, c.Level)
+}
+
+// Stat returns the stat of its column name: strength, agility, wisdom or constitution
+func (c *Character) Stat(stat string) (*int, error) {
+	switch stat {
+	case "strength":
+		return &c.Strength, nil
+	case "agility":
+		return &c.Agility, nil
+	case "wisdom":
+		return &c.Wisdom, nil
+	case "constitution":
+		return &c.Constitution, nil
+	}
+	return nil, errWrongStat
+}
+
+// AddStat spends amount skill points in a stat, up to the stat cap of the level
+func (c *Character) AddStat(curve *progression.Curve, stat string, amount int) error {
+	value, err := c.Stat(stat)
+	if err != nil {
+		return err
 	}
 
-	return str
+	if amount > c.SkillPoints {
+		return fmt.Errorf("%d points left: %w", c.SkillPoints, ErrNotEnoughSkillPoints)
+	}
+	if max := curve.StatCap(c.Level); max > 0 && *value+amount > max {
+		return fmt.Errorf("%s capped at %d: %w", stat, max, ErrStatCapped)
+	}
+
+	*value += amount
+	c.SkillPoints -= amount
+	return nil
+}
+
+// Respec refunds the skill points spent in the stats, the HP above the new max are lost
+func (c *Character) Respec(curve *progression.Curve) error {
+	refund := 0
+	for _, stat := range []*int{&c.Strength, &c.Agility, &c.Wisdom, &c.Constitution} {
+		refund += *stat - BaseStat
+		*stat = BaseStat
+	}
+	if refund <= 0 {
+		return ErrNothingToRefund
+	}
+
+	c.SkillPoints += refund
+	if c.CurrentHp > c.GetMaxHP(curve) {
+		c.CurrentHp = c.GetMaxHP(curve)
+	}
+	return nil
+}
+
+// IsKnockedOut tells if the character is unable to act until revived or rested
+func (c Character) IsKnockedOut() bool {
+	return c.CurrentHp <= 0
 }
 
-func (c Character) GetMaxHP() int {
-	return 10 + c.Constitution + c.Level
+// Regenerate applies the stamina and HP regained since the last regeneration.
+// Knocked out characters do not regain HP, they have to rest or be revived.
+func (c *Character) Regenerate(curve *progression.Curve, now time.Time) {
+	if c.RegeneratedAt.IsZero() {
+		c.RegeneratedAt = now
+		return
+	}
+
+	ticks := int(now.Sub(c.RegeneratedAt) / RegenInterval)
+	if ticks > 0 {
+		c.RegeneratedAt = c.RegeneratedAt.Add(time.Duration(ticks) * RegenInterval)
+
+		c.Stamina += ticks * StaminaRegen
+		if c.Stamina > MaxStamina {
+			c.Stamina = MaxStamina
+		}
+
+		if !c.IsKnockedOut() {
+			c.CurrentHp += ticks * HPRegen
+			if c.CurrentHp > c.GetMaxHP(curve) {
+				c.CurrentHp = c.GetMaxHP(curve)
+			}
+		}
+	}
+
+	// Nothing to regain: the time spent fully rested is not banked
+	if c.Stamina >= MaxStamina && c.CurrentHp >= c.GetMaxHP(curve) {
+		c.RegeneratedAt = now
+	}
 }
 
-func NewCharacter() Character {
+// NewCharacter creates a level 1 character. Without class, the character fights
+// until the player chooses one.
+func NewCharacter(curve *progression.Curve, class string) Character {
 	c := Character{
-		Class:        "Combattant",
+		Class:        class,
+		ClassChosen:  class != "",
 		Experience:   0,
 		Level:        1,
-		Strength:     1,
-		Agility:      1,
-		Wisdom:       1,
-		Constitution: 1,
+		Strength:     BaseStat,
+		Agility:      BaseStat,
+		Wisdom:       BaseStat,
+		Constitution: BaseStat,
 		SkillPoints:  5,
-		Stamina:      100,
+		Stamina:      MaxStamina,
 	}
-	c.CurrentHp = c.GetMaxHP()
+	if class == "" {
+		c.Class = ClassFighter
+	}
+	c.CurrentHp = c.GetMaxHP(curve)
 	return c
 }
 
-func (db *DB) FetchCharacters() (string, error) {
-	characters := []Character{}
-	result := db.Select("ID", "level").Find(&characters)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return "", result.Error
-	}
-
-	charactersString := ""
-	for i := range characters {
-		character := &characters[i]
-		charactersString += util.DiscordIDToText(character.ID) + " (niv. " + strconv.Itoa(character.Level) + ") "
-	}
-	return charactersString, nil
+// FetchCharacters returns the characters of a campaign
+func (db *DB) FetchCharacters(campaignID uint) (characters []Character, e error) {
+	e = db.Where("campaign_id = ?", campaignID).Order("id").Find(&characters).Error
+	return
 }
 
-func (db *DB) FetchCharacterInfo(userID uint) (c Character, e error) {
-	e = db.First(&c, userID).Error
+// FetchCharacterInfo returns the character of a user in a campaign
+func (db *DB) FetchCharacterInfo(campaignID uint, userID uint) (c Character, e error) {
+	e = db.Where("campaign_id = ? AND user_id = ?", campaignID, userID).First(&c).Error
 	return
 }
 
-func (db *DB) CreateCharacter(discordID uint) error {
-	characterToCreate := NewCharacter()
-	characterToCreate.ID = discordID
+func (db *DB) CreateCharacter(curve *progression.Curve, campaignID uint, userID uint, class string) error {
+	tx := db.begin()
+	defer tx.Rollback()
 
-	result := db.Create(&characterToCreate)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return result.Error
+	_, err := tx.FetchCharacterInfo(campaignID, userID)
+	if err == nil {
+		return errCharacterAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
 	}
 
-	return nil
+	characterToCreate := NewCharacter(curve, class)
+	characterToCreate.CampaignID = campaignID
+	characterToCreate.UserID = userID
+
+	// a concurrent join of the same player is stopped by the unique index
+	if err := tx.Create(&characterToCreate).Error; err != nil {
+		if isUniqueViolation(err) {
+			return errCharacterAlreadyExists
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) SaveCharacter(c *Character) error {
+	return db.Save(c).Error
+}
+
+// UpdateCharacter writes only the columns of the character an action changed,
+// so a concurrent change of the other columns, like a purchase, is not overwritten
+func (db *DB) UpdateCharacter(c *Character, columns ...string) error {
+	return db.Model(c).Select(columns).Updates(c).Error
 }
 
-func (db *DB) UpStats(statsToUp string, userID uint, amount int) error {
+func (db *DB) UpStats(curve *progression.Curve, statsToUp string, campaignID uint, userID uint, amount int) error {
 	tx := db.Begin()
 	defer tx.Rollback()
 
-	character, err := tx.FetchCharacterInfo(userID)
+	character, err := tx.FetchCharacterInfo(campaignID, userID)
 	if err != nil {
 		return err
 	}
 
-	if amount > character.SkillPoints {
-		return errNotEnoughSkillPoints
-	}
-
-	switch statsToUp {
-	case "strength":
-		character.Strength = character.Strength + amount
-	case "agility":
-		character.Agility = character.Agility + amount
-	case "wisdom":
-		character.Wisdom = character.Wisdom + amount
-	case "constitution":
-		character.Constitution = character.Constitution + amount
-	default:
-		return errWrongStat
+	if err := character.AddStat(curve, statsToUp, amount); err != nil {
+		return err
 	}
 
-	character.SkillPoints = character.SkillPoints - amount
-
-	if err := tx.Save(&character).Error; err != nil {
+	if err := tx.UpdateCharacter(&character, StatColumns...); err != nil {
 		return err
 	}
 
-	return tx.Commit().Error
+	return tx.Commit()
 }
@@ -0,0 +1,282 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+)
+
+func newTestStore(t *testing.T) *DB {
+	t.Helper()
+
+	store, err := NewSQLite("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.DB = store.DB.Session(&gorm.Session{Logger: logger.Discard})
+	t.Cleanup(func() {
+		if sqlDB, err := store.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return store
+}
+
+func TestUpStats(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.CreateCharacter(progression.Default(), 1, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UpStats(progression.Default(), "agility", 1, 10, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpStats(progression.Default(), "agility", 1, 10, 4); !errors.Is(err, ErrNotEnoughSkillPoints) {
+		t.Errorf("expected not enough skill points, got %v", err)
+	}
+	if err := store.UpStats(progression.Default(), "charisma", 1, 10, 1); !errors.Is(err, errWrongStat) {
+		t.Errorf("expected wrong stat, got %v", err)
+	}
+
+	c, err := store.FetchCharacterInfo(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Agility != 3 || c.SkillPoints != 3 {
+		t.Errorf("unexpected character %+v", c)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.SpawnMonster(&Monster{CampaignID: 1, Name: "Rat", CurrentHp: 11}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := store.Begin()
+	m, err := tx.FetchMonsterInfo(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.CurrentHp = 0
+	if err := tx.UpdateMonsterHP(&m); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = store.FetchMonsterInfo(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CurrentHp != 11 {
+		t.Errorf("expected the update to be rolled back, got %d HP", m.CurrentHp)
+	}
+}
+
+func TestStatCap(t *testing.T) {
+	curve := progression.Default()
+	c := NewCharacter(curve, "")
+	c.SkillPoints = 20
+
+	// 5 + 3 per level with the default progression
+	if err := c.AddStat(curve, "strength", 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddStat(curve, "strength", 1); !errors.Is(err, ErrStatCapped) {
+		t.Errorf("expected strength to be capped at 8, got %v", err)
+	}
+
+	c.Level = 2
+	if err := c.AddStat(curve, "strength", 3); err != nil || c.Strength != 11 || c.SkillPoints != 10 {
+		t.Errorf("expected the cap to grow with the level, got %+v, %v", c, err)
+	}
+}
+
+func TestRespec(t *testing.T) {
+	curve := progression.Default()
+	c := NewCharacter(curve, "")
+	if err := c.Respec(curve); !errors.Is(err, ErrNothingToRefund) {
+		t.Errorf("expected nothing to refund, got %v", err)
+	}
+
+	for _, stat := range []string{"agility", "constitution"} {
+		if err := c.AddStat(curve, stat, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.CurrentHp = c.GetMaxHP(curve)
+
+	if err := c.Respec(curve); err != nil {
+		t.Fatal(err)
+	}
+	if c.Agility != BaseStat || c.Constitution != BaseStat || c.SkillPoints != 5 || c.CurrentHp != c.GetMaxHP(curve) {
+		t.Errorf("expected every point back and the HP within the new max, got %+v", c)
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	start := time.Date(2020, 12, 1, 20, 0, 0, 0, time.UTC)
+
+	curve := progression.Default()
+	c := NewCharacter(curve, "")
+	c.Regenerate(curve, start)
+	if !c.RegeneratedAt.Equal(start) {
+		t.Fatalf("expected the regeneration to start at %v, got %v", start, c.RegeneratedAt)
+	}
+
+	// fully rested: nothing is banked
+	c.Regenerate(curve, start.Add(time.Hour))
+	c.Stamina = 10
+	c.CurrentHp = 5
+	c.Regenerate(curve, start.Add(time.Hour+150*time.Second))
+	if c.Stamina != 14 || c.CurrentHp != 7 {
+		t.Errorf("expected 2 ticks of regeneration, got %d stamina and %d HP", c.Stamina, c.CurrentHp)
+	}
+
+	// the remaining 30s count for the next tick
+	c.Regenerate(curve, start.Add(time.Hour+180*time.Second))
+	if c.Stamina != 16 || c.CurrentHp != 8 {
+		t.Errorf("expected a third tick of regeneration, got %d stamina and %d HP", c.Stamina, c.CurrentHp)
+	}
+
+	c.Regenerate(curve, start.Add(10*time.Hour))
+	if c.Stamina != MaxStamina || c.CurrentHp != c.GetMaxHP(curve) {
+		t.Errorf("expected a full regeneration, got %d stamina and %d HP", c.Stamina, c.CurrentHp)
+	}
+
+	// knocked out characters only regain stamina
+	c.CurrentHp = 0
+	c.Stamina = 0
+	c.Regenerate(curve, start.Add(11*time.Hour))
+	if c.Stamina != MaxStamina || !c.IsKnockedOut() {
+		t.Errorf("expected a knocked out character with full stamina, got %+v", c)
+	}
+}
+
+func TestBuyCannotOverspend(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.CreateCharacter(progression.Default(), 1, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	c, err := store.FetchCharacterInfo(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddGold(c.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StockItem(1, "potion", 4, UnlimitedStock); err != nil {
+		t.Fatal(err)
+	}
+
+	bought := 0
+	for i := 0; i < 5; i++ {
+		_, err := store.Buy(1, c.ID, "potion", 1)
+		if err == nil {
+			bought++
+			continue
+		}
+		if !errors.Is(err, ErrNotEnoughGold) {
+			t.Fatal(err)
+		}
+	}
+
+	c, err = store.FetchCharacterInfo(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bought != 2 || c.Gold != 2 {
+		t.Errorf("expected 2 potions bought and 2 gold left, got %d and %d", bought, c.Gold)
+	}
+
+	// a failed purchase does not give the item
+	inventory, err := store.FetchInventory(c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inventory) != 1 || inventory[0].Quantity != 2 {
+		t.Errorf("unexpected inventory %+v", inventory)
+	}
+}
+
+func TestAddItemStacks(t *testing.T) {
+	store := newTestStore(t)
+	for _, quantity := range []int{2, 3} {
+		if err := store.AddItem(1, "potion", quantity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inventory, err := store.FetchInventory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inventory) != 1 || inventory[0].Quantity != 5 {
+		t.Errorf("expected a single stack of 5 potions, got %+v", inventory)
+	}
+
+	// a concurrent drop cannot open a second stack
+	if err := store.Create(&InventoryItem{CharacterID: 1, Item: "potion", Quantity: 1}).Error; err == nil {
+		t.Error("expected the stack to be unique")
+	}
+}
+
+func TestUpdateCharacterKeepsGold(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.CreateCharacter(progression.Default(), 1, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := store.FetchCharacterInfo(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddGold(stale.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StockItem(1, "potion", 4, UnlimitedStock); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Buy(1, stale.ID, "potion", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// an action started before the purchase saves its character afterwards
+	stale.Stamina -= 10
+	if err := store.UpdateCharacter(&stale, RegenColumns...); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := store.FetchCharacterInfo(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Gold != 6 || c.Stamina != MaxStamina-10 {
+		t.Errorf("expected 6 gold and the stamina spent, got %+v", c)
+	}
+}
+
+func TestOneCharacterPerCampaign(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.CreateCharacter(progression.Default(), 1, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// a concurrent join passed the lookup, the index stops the insert
+	duplicate := NewCharacter(progression.Default(), "")
+	duplicate.CampaignID = 1
+	duplicate.UserID = 10
+	if err := store.Create(&duplicate).Error; !isUniqueViolation(err) {
+		t.Errorf("expected a unique violation, got %v", err)
+	}
+
+	if err := store.CreateCharacter(progression.Default(), 2, 10, ""); err != nil {
+		t.Errorf("a player can join another campaign, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"strings"
+)
+
+// Character classes
+const (
+	ClassFighter = "Combattant"
+	ClassMage    = "Mage"
+	ClassHealer  = "Soigneur"
+)
+
+// Classes lists the playable classes
+var Classes = []string{ClassFighter, ClassMage, ClassHealer} //nolint:gochecknoglobals
+
+// classAliases maps the accepted spellings to a class
+var classAliases = map[string]string{ //nolint:gochecknoglobals
+	"combattant": ClassFighter,
+	"fighter":    ClassFighter,
+	"mage":       ClassMage,
+	"soigneur":   ClassHealer,
+	"healer":     ClassHealer,
+}
+
+// ParseClass returns the class matching name, case insensitive
+func ParseClass(name string) (string, bool) {
+	class, ok := classAliases[strings.ToLower(strings.TrimSpace(name))]
+	return class, ok
+}
+
+// ChooseClass sets the class of a character who has not chosen one yet
+func (db *DB) ChooseClass(campaignID uint, userID uint, class string) error {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	character, err := tx.FetchCharacterInfo(campaignID, userID)
+	if err != nil {
+		return err
+	}
+
+	if character.ClassChosen {
+		return ErrClassAlreadyChosen
+	}
+
+	character.Class = class
+	character.ClassChosen = true
+
+	if err := tx.UpdateCharacter(&character, "class", "class_chosen"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -5,19 +5,48 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/jackc/pgconn"
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+
+	"github.com/vincent-heng/discord-airpgbot/config"
 )
 
+// DB is the SQL implementation of Store, backed by Postgres or SQLite
 type DB struct {
 	*gorm.DB
 }
 
 const (
 	dbName = "rpg"
+
+	// StoragePostgres is the default storage, configured by DB_* env vars
+	StoragePostgres = "postgres"
+	// StorageSQLite is an embedded storage, in memory unless a file is configured
+	StorageSQLite = "sqlite"
+
+	sqliteInMemory = ":memory:"
+
+	// pgUniqueViolation is the Postgres error code of a duplicate key
+	pgUniqueViolation = "23505"
 )
 
-func New() (*DB, error) {
+// New opens the storage selected in configuration
+func New(conf config.Config) (*DB, error) {
+	switch conf.Storage {
+	case "", StoragePostgres:
+		return NewPostgres()
+	case StorageSQLite:
+		return NewSQLite(conf.SQLiteFile)
+	default:
+		return nil, fmt.Errorf("storage %q: %w", conf.Storage, errUnknownStorage)
+	}
+}
+
+// NewPostgres connects to the Postgres database described by DB_HOST, DB_USER and DB_PASSWORD
+func NewPostgres() (*DB, error) {
 	dbHost := os.Getenv("DB_HOST")
 	dbUser := os.Getenv("DB_USER")
 	dbPassword := os.Getenv("DB_PASSWORD")
@@ -30,8 +59,40 @@ func New() (*DB, error) {
 		return nil, err
 	}
 
+	return migrate(db)
+}
+
+// NewSQLite opens an embedded database stored in file, or in memory if file is empty
+func NewSQLite(file string) (*DB, error) {
+	if file == "" {
+		file = sqliteInMemory
+	}
+
+	db, err := gorm.Open(sqlite.Open(file), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// A single connection: every connection to ":memory:" is a distinct database,
+	// and SQLite does not handle concurrent writers anyway
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return migrate(db)
+}
+
+func migrate(db *gorm.DB) (*DB, error) {
+	// the participations keep the damage dealt by each character
+	if e := db.SetupJoinTable(&Monster{}, "Participants", &BattleParticipation{}); e != nil {
+		return nil, fmt.Errorf("battle participations setup failed: %w", e)
+	}
+
 	for _, table := range []interface{}{
-		&Character{}, &Monster{},
+		&Character{}, &Monster{}, &BattleRoll{}, &InventoryItem{}, &ShopItem{}, &Campaign{}, &Adventure{}, &Permission{},
+		&Player{}, &BattleEvent{},
 	} {
 		if e := db.AutoMigrate(table); e != nil {
 			return nil, fmt.Errorf("automigrate %+v failed: %w", table, e)
@@ -40,25 +101,76 @@ func New() (*DB, error) {
 	return &DB{DB: db}, nil
 }
 
-func (db *DB) Begin() *DB {
+// isUniqueViolation tells if err is a duplicate key on a unique index, in Postgres or SQLite
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	return false
+}
+
+func (db *DB) Begin() Store {
+	return db.begin()
+}
+
+func (db *DB) begin() *DB {
 	return &DB{
 		DB: db.DB.Begin(),
 	}
 }
 
-func (db *DB) GetParticipants(characterDiscordID uint) (*Character, *Monster, error) {
-	attacker, err := db.FetchCharacterInfo(characterDiscordID)
+func (db *DB) Commit() error {
+	return db.DB.Commit().Error
+}
+
+func (db *DB) Rollback() error {
+	return db.DB.Rollback().Error
+}
+
+// GetParticipants loads the attacker and the targeted monster of the campaign encounter, see FindTarget
+func (db *DB) GetParticipants(campaignID uint, userID uint, target string) (*Character, *Monster, error) {
+	attacker, err := db.FetchCharacterInfo(campaignID, userID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot get character info: %w", err)
 	}
 
-	monsterTarget, err := db.FetchMonsterInfo()
+	monsters, err := db.FetchMonsters(campaignID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot load monsters: %w", err)
+	}
+
+	monsterTarget, err := FindTarget(monsters, target)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil, fmt.Errorf("monster not found: %w", err)
 	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("cannot load monster: %w", err)
+		return nil, nil, fmt.Errorf("cannot find target %q: %w", target, err)
+	}
+
+	return &attacker, monsterTarget, nil
+}
+
+// AddParticipant enrolls the character in the battle against m, and adds the damage they dealt to their record
+func (db *DB) AddParticipant(m *Monster, c *Character, damage int) error {
+	if err := db.Model(m).Association("Participants").Append(c); err != nil {
+		return err
 	}
+	return db.Model(&BattleParticipation{}).Where("monster_id = ? AND character_id = ?", m.ID, c.ID).
+		Update("damage", gorm.Expr("damage + ?", damage)).Error
+}
+
+func (db *DB) FetchParticipants(m *Monster) (participants []Character, e error) {
+	e = db.Model(m).Association("Participants").Find(&participants)
+	return
+}
 
-	return &attacker, &monsterTarget, nil
+// FetchParticipations returns the records of the characters who fought m
+func (db *DB) FetchParticipations(m *Monster) (participations []BattleParticipation, e error) {
+	e = db.Where("monster_id = ?", m.ID).Find(&participations).Error
+	return
 }
@@ -3,6 +3,17 @@ package db
 import "errors"
 
 var (
-	errNotEnoughSkillPoints = errors.New("not enough skill points")
-	errWrongStat            = errors.New("wrong stat")
+	errWrongStat              = errors.New("wrong stat")
+	errUnknownStorage         = errors.New("unknown storage")
+	errCharacterAlreadyExists = errors.New("character already exists")
+	ErrClassAlreadyChosen     = errors.New("class already chosen")
+	ErrUnknownTarget          = errors.New("unknown target")
+	ErrItemNotOwned           = errors.New("item not owned")
+	ErrNotForSale             = errors.New("item not for sale")
+	ErrOutOfStock             = errors.New("out of stock")
+	ErrNotEnoughGold          = errors.New("not enough gold")
+	ErrNoPermission           = errors.New("no permission granted")
+	ErrNotEnoughSkillPoints   = errors.New("not enough skill points")
+	ErrStatCapped             = errors.New("stat capped")
+	ErrNothingToRefund        = errors.New("no skill point spent")
 )
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InventoryItem is a stack of items owned by a character
+type InventoryItem struct {
+	gorm.Model
+	CharacterID uint   `gorm:"uniqueIndex:idx_inventory_character_item"`
+	Item        string `gorm:"uniqueIndex:idx_inventory_character_item"` // key in the item catalog
+	Quantity    int
+	Equipped    bool
+}
+
+func (db *DB) FetchInventory(characterID uint) (inventory []InventoryItem, e error) {
+	e = db.Where("character_id = ?", characterID).Order("item").Find(&inventory).Error
+	return
+}
+
+func (db *DB) fetchInventoryItem(characterID uint, item string) (i InventoryItem, e error) {
+	e = db.Where("character_id = ? AND item = ?", characterID, item).First(&i).Error
+	return
+}
+
+// AddItem gives quantity items to the character. The stack is created or incremented in a single upsert,
+// so concurrent purchases and drops neither duplicate it nor lose a quantity.
+func (db *DB) AddItem(characterID uint, item string, quantity int) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "character_id"}, {Name: "item"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity": gorm.Expr("inventory_items.quantity + ?", quantity),
+		}),
+	}).Create(&InventoryItem{CharacterID: characterID, Item: item, Quantity: quantity}).Error
+}
+
+// RemoveItem takes quantity items from the character. The quantity is decremented
+// with a conditional update, so concurrent removals cannot take more than owned.
+func (db *DB) RemoveItem(characterID uint, item string, quantity int) error {
+	result := db.Model(&InventoryItem{}).
+		Where("character_id = ? AND item = ? AND quantity >= ?", characterID, item, quantity).
+		Update("quantity", gorm.Expr("quantity - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrItemNotOwned
+	}
+
+	return db.Unscoped().
+		Where("character_id = ? AND item = ? AND quantity <= 0", characterID, item).
+		Delete(&InventoryItem{}).Error
+}
+
+// SetEquipped equips or unequips an item owned by the character
+func (db *DB) SetEquipped(characterID uint, item string, equipped bool) error {
+	stack, err := db.fetchInventoryItem(characterID, item)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrItemNotOwned
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&stack).Update("equipped", equipped).Error
+}
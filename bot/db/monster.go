@@ -2,35 +2,136 @@ package db
 
 import (
 	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+const hpBarWidth = 10
+
+const (
+	// MonsterBaseHP is the max HP of a monster without constitution
+	MonsterBaseHP = 10
+	// MinMonsterConstitution leaves a monster 1 HP
+	MinMonsterConstitution = 1 - MonsterBaseHP
 )
 
 type Monster struct {
 	gorm.Model
-	Name         string
+	CampaignID uint `gorm:"index"`
+	Name       string
+	// Template is the bestiary key of the monster, empty for custom monsters
+	Template     string
 	Experience   int
+	Gold         int
 	Strength     int
 	Agility      int
 	Wisdom       int
 	Constitution int
 	CurrentHp    int
+	// Seed of the battle dice, and number of rolls already drawn
+	Seed         int64
+	Rolls        int
 	Participants []*Character `gorm:"many2many:battle_participations;"`
 }
 
+// BattleParticipation records a character fighting a monster, joining Monster.Participants
+type BattleParticipation struct {
+	MonsterID   uint `gorm:"primaryKey"`
+	CharacterID uint `gorm:"primaryKey"`
+	// Damage is the HP the character took from the monster
+	Damage int `gorm:"not null;default:0"`
+}
+
 func (m Monster) String() string {
-	return m.Name + " - " + strconv.Itoa(m.CurrentHp) + " / " + strconv.Itoa(m.GetMaxHP()) + " HP\n"
+	return m.Name + " - " + strconv.Itoa(m.CurrentHp) + " / " + strconv.Itoa(m.GetMaxHP()) + " HP " +
+		util.ProgressBar(m.CurrentHp, m.GetMaxHP(), hpBarWidth) + "\n"
 }
 
 func (m Monster) GetMaxHP() int {
-	return 10 + m.Constitution
+	return MonsterBaseHP + m.Constitution
+}
+
+// ValidStats tells if the monster can fight: no negative stat nor reward, and at least 1 HP
+func (m Monster) ValidStats() bool {
+	for _, stat := range []int{m.Experience, m.Gold, m.Strength, m.Agility, m.Wisdom} {
+		if stat < 0 {
+			return false
+		}
+	}
+	return m.Constitution >= MinMonsterConstitution
+}
+
+func (db *DB) FetchMonsterInfo(campaignID uint) (m Monster, e error) {
+	e = db.Where("campaign_id = ? AND current_hp > 0", campaignID).First(&m).Error
+	return
+}
+
+// FetchMonsters returns the current encounter of the campaign: every monster alive, in spawn order
+// FetchFoughtMonster returns the living monster of the campaign that the character fights, the first one if several
+func (db *DB) FetchFoughtMonster(campaignID uint, characterID uint) (m Monster, e error) {
+	fighting := db.Session(&gorm.Session{NewDB: true}).Table("battle_participations").
+		Select("monster_id").Where("character_id = ?", characterID)
+	e = db.Where("campaign_id = ? AND current_hp > 0 AND id IN (?)", campaignID, fighting).Order("id").First(&m).Error
+	return
 }
 
-func (db *DB) FetchMonsterInfo() (m Monster, e error) {
-	e = db.Where("current_hp > 0").First(&m).Error
+func (db *DB) FetchMonsters(campaignID uint) (monsters []Monster, e error) {
+	e = db.Where("campaign_id = ? AND current_hp > 0", campaignID).Order("id").Find(&monsters).Error
 	return
 }
 
-func (db *DB) SpawnMonster(m Monster) error {
-	return db.Create(&m).Error
+// FindTarget picks a monster of the encounter by its position (starting at 1) or its name.
+// An empty target is the first monster.
+func FindTarget(monsters []Monster, target string) (*Monster, error) {
+	if len(monsters) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return &monsters[0], nil
+	}
+
+	if index, err := strconv.Atoi(target); err == nil {
+		if index < 1 || index > len(monsters) {
+			return nil, ErrUnknownTarget
+		}
+		return &monsters[index-1], nil
+	}
+
+	for i := range monsters {
+		if strings.EqualFold(monsters[i].Name, target) {
+			return &monsters[i], nil
+		}
+	}
+	for i := range monsters {
+		if strings.HasPrefix(strings.ToLower(monsters[i].Name), strings.ToLower(target)) {
+			return &monsters[i], nil
+		}
+	}
+
+	return nil, ErrUnknownTarget
+}
+
+// FetchMonster returns a monster of the campaign by ID, dead or alive
+func (db *DB) FetchMonster(campaignID uint, id uint) (m Monster, e error) {
+	e = db.Where("campaign_id = ?", campaignID).First(&m, id).Error
+	return
+}
+
+// FetchLastDefeatedMonster returns the last monster killed in the campaign
+func (db *DB) FetchLastDefeatedMonster(campaignID uint) (m Monster, e error) {
+	e = db.Where("campaign_id = ? AND current_hp <= 0", campaignID).Order("updated_at desc").First(&m).Error
+	return
+}
+
+func (db *DB) SpawnMonster(m *Monster) error {
+	return db.Create(m).Error
+}
+
+func (db *DB) UpdateMonsterHP(m *Monster) error {
+	return db.Model(&Monster{Model: gorm.Model{ID: m.ID}}).Update("current_hp", m.CurrentHp).Error
 }
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+)
+
+func TestFindTarget(t *testing.T) {
+	monsters := []Monster{{Name: "Goblin"}, {Name: "Goblin chief"}, {Name: "Rat"}}
+
+	cases := []struct {
+		target string
+		index  int
+		err    error
+	}{
+		{"", 0, nil},
+		{"2", 1, nil},
+		{"3", 2, nil},
+		{"0", 0, ErrUnknownTarget},
+		{"4", 0, ErrUnknownTarget},
+		{"goblin", 0, nil},
+		{"GOBLIN CHIEF", 1, nil},
+		{"ra", 2, nil},
+		{"dragon", 0, ErrUnknownTarget},
+	}
+	for _, c := range cases {
+		m, err := FindTarget(monsters, c.target)
+		if !errors.Is(err, c.err) {
+			t.Errorf("FindTarget(%q): expected error %v, got %v", c.target, c.err, err)
+			continue
+		}
+		if err == nil && m != &monsters[c.index] {
+			t.Errorf("FindTarget(%q): expected %q, got %q", c.target, monsters[c.index].Name, m.Name)
+		}
+	}
+
+	if _, err := FindTarget(nil, ""); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected no monster, got %v", err)
+	}
+}
+
+func TestParticipationDamage(t *testing.T) {
+	store := newTestStore(t)
+	for _, userID := range []uint{10, 11} {
+		if err := store.CreateCharacter(progression.Default(), 1, userID, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := Monster{CampaignID: 1, Name: "Boss", CurrentHp: 50}
+	if err := store.SpawnMonster(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := store.FetchCharacterInfo(1, 10)
+	second, _ := store.FetchCharacterInfo(1, 11)
+	for _, hit := range []struct {
+		c      *Character
+		damage int
+	}{{&first, 4}, {&second, 7}, {&first, 3}} {
+		if err := store.AddParticipant(&m, hit.c, hit.damage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	participants, err := store.FetchParticipants(&m)
+	if err != nil || len(participants) != 2 {
+		t.Fatalf("expected 2 participants, got %v (%v)", participants, err)
+	}
+
+	participations, err := store.FetchParticipations(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	damage := map[uint]int{}
+	for _, p := range participations {
+		damage[p.CharacterID] = p.Damage
+	}
+	if damage[first.ID] != 7 || damage[second.ID] != 7 || len(damage) != 2 {
+		t.Errorf("unexpected damage %v", damage)
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Role grants access to the restricted commands of a campaign
+type Role string
+
+const (
+	// RolePlayer is the role of everyone
+	RolePlayer     Role = "player"
+	RoleModerator  Role = "moderator"
+	RoleGameMaster Role = "gm"
+)
+
+var roleAliases = map[string]Role{ //nolint:gochecknoglobals
+	"player":      RolePlayer,
+	"joueur":      RolePlayer,
+	"moderator":   RoleModerator,
+	"mod":         RoleModerator,
+	"modo":        RoleModerator,
+	"gm":          RoleGameMaster,
+	"mj":          RoleGameMaster,
+	"game_master": RoleGameMaster,
+}
+
+// ParseRole returns the role matching name, case insensitive
+func ParseRole(name string) (Role, bool) {
+	r, ok := roleAliases[strings.ToLower(strings.TrimSpace(name))]
+	return r, ok
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleGameMaster:
+		return 2
+	case RoleModerator:
+		return 1
+	}
+	return 0
+}
+
+// Includes tells if r grants every right of other: a game master can moderate
+func (r Role) Includes(other Role) bool {
+	return r.rank() >= other.rank()
+}
+
+// Permission grants a role in a campaign, either to a user or to the members of a Discord role
+type Permission struct {
+	gorm.Model
+	CampaignID uint `gorm:"index"`
+	// UserID is the discord ID of the user, 0 when granted to a Discord role
+	UserID uint
+	// RoleID is the Discord role, empty when granted to a user
+	RoleID string
+	Role   Role
+}
+
+// FetchPermissions returns the roles granted in a campaign
+func (db *DB) FetchPermissions(campaignID uint) (permissions []Permission, e error) {
+	e = db.Where("campaign_id = ?", campaignID).Order("id").Find(&permissions).Error
+	return
+}
+
+// Grant gives a role to the user or the Discord role of p, replacing their previous role
+func (db *DB) Grant(p *Permission) error {
+	existing := Permission{}
+	err := db.Where("campaign_id = ? AND user_id = ? AND role_id = ?", p.CampaignID, p.UserID, p.RoleID).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Create(p).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.Role = p.Role
+	*p = existing
+	return db.Save(p).Error
+}
+
+// Revoke removes the role granted to a user or a Discord role
+func (db *DB) Revoke(campaignID uint, userID uint, roleID string) error {
+	result := db.Unscoped().
+		Where("campaign_id = ? AND user_id = ? AND role_id = ?", campaignID, userID, roleID).
+		Delete(&Permission{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoPermission
+	}
+	return nil
+}
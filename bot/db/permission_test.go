@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoleIncludes(t *testing.T) {
+	cases := []struct {
+		role  Role
+		other Role
+		want  bool
+	}{
+		{RoleGameMaster, RoleModerator, true},
+		{RoleGameMaster, RoleGameMaster, true},
+		{RoleModerator, RoleGameMaster, false},
+		{RoleModerator, RolePlayer, true},
+		{RolePlayer, RoleModerator, false},
+		{Role(""), RolePlayer, true},
+	}
+	for _, c := range cases {
+		if got := c.role.Includes(c.other); got != c.want {
+			t.Errorf("%q includes %q: expected %v, got %v", c.role, c.other, c.want, got)
+		}
+	}
+}
+
+func TestGrantAndRevoke(t *testing.T) {
+	store := newTestStore(t)
+
+	if err := store.Grant(&Permission{CampaignID: 1, UserID: 10, Role: RoleModerator}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Grant(&Permission{CampaignID: 1, RoleID: "55", Role: RoleGameMaster}); err != nil {
+		t.Fatal(err)
+	}
+	// a new grant replaces the previous role
+	if err := store.Grant(&Permission{CampaignID: 1, UserID: 10, Role: RoleGameMaster}); err != nil {
+		t.Fatal(err)
+	}
+
+	permissions, err := store.FetchPermissions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(permissions) != 2 || permissions[0].Role != RoleGameMaster || permissions[1].RoleID != "55" {
+		t.Errorf("unexpected permissions %+v", permissions)
+	}
+
+	if err := store.Revoke(1, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke(1, 10, ""); !errors.Is(err, ErrNoPermission) {
+		t.Errorf("expected no permission, got %v", err)
+	}
+	if err := store.Revoke(2, 0, "55"); !errors.Is(err, ErrNoPermission) {
+		t.Errorf("expected permissions to be scoped by campaign, got %v", err)
+	}
+}
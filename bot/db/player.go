@@ -0,0 +1,30 @@
+package db
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Player holds the settings of a Discord user, shared by every campaign they play
+type Player struct {
+	gorm.Model
+	// UserID is the discord ID of the player
+	UserID uint `gorm:"uniqueIndex"`
+	// Locale is the language of the answers to the player, the one of the campaign when empty
+	Locale string
+}
+
+// FetchPlayer returns the settings of a user, empty ones when they never changed them
+func (db *DB) FetchPlayer(userID uint) (Player, error) {
+	p := Player{}
+	err := db.Where("user_id = ?", userID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Player{UserID: userID}, nil
+	}
+	return p, err
+}
+
+func (db *DB) SavePlayer(p *Player) error {
+	return db.Save(p).Error
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// UnlimitedStock is the stock of an item the shop never runs out of
+const UnlimitedStock = -1
+
+// ShopItem is an item sold by the NPC shop, at a price set by the game master
+type ShopItem struct {
+	gorm.Model
+	CampaignID uint   `gorm:"index"`
+	Item       string // key in the item catalog
+	Price      int
+	Stock      int
+}
+
+// SellPrice is the price the shop pays for the item
+func (i ShopItem) SellPrice() int {
+	return i.Price / 2
+}
+
+func (db *DB) FetchShop(campaignID uint) (shop []ShopItem, e error) {
+	e = db.Where("campaign_id = ?", campaignID).Order("item").Find(&shop).Error
+	return
+}
+
+func (db *DB) fetchShopItem(campaignID uint, item string) (i ShopItem, e error) {
+	e = db.Where("campaign_id = ? AND item = ?", campaignID, item).First(&i).Error
+	if errors.Is(e, gorm.ErrRecordNotFound) {
+		e = ErrNotForSale
+	}
+	return
+}
+
+// StockItem puts an item on sale, or changes its price and stock
+func (db *DB) StockItem(campaignID uint, item string, price int, stock int) error {
+	shopItem, err := db.fetchShopItem(campaignID, item)
+	if errors.Is(err, ErrNotForSale) {
+		return db.Create(&ShopItem{CampaignID: campaignID, Item: item, Price: price, Stock: stock}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	shopItem.Price = price
+	shopItem.Stock = stock
+	return db.Save(&shopItem).Error
+}
+
+// UnstockItem removes an item from the shop
+func (db *DB) UnstockItem(campaignID uint, item string) error {
+	result := db.Unscoped().Where("campaign_id = ? AND item = ?", campaignID, item).Delete(&ShopItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotForSale
+	}
+	return nil
+}
+
+// Buy pays quantity items from the campaign shop and gives them to the character, returning the price paid.
+// Gold and stock are decremented with conditional updates, so concurrent purchases cannot overspend.
+func (db *DB) Buy(campaignID uint, characterID uint, item string, quantity int) (int, error) {
+	tx := db.begin()
+	defer tx.Rollback()
+
+	shopItem, err := tx.fetchShopItem(campaignID, item)
+	if err != nil {
+		return 0, err
+	}
+	price := shopItem.Price * quantity
+
+	if shopItem.Stock != UnlimitedStock {
+		result := tx.Model(&ShopItem{}).
+			Where("id = ? AND stock >= ?", shopItem.ID, quantity).
+			Update("stock", gorm.Expr("stock - ?", quantity))
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, ErrOutOfStock
+		}
+	}
+
+	if err := tx.AddGold(characterID, -price); err != nil {
+		return 0, err
+	}
+
+	if err := tx.AddItem(characterID, item, quantity); err != nil {
+		return 0, err
+	}
+
+	return price, tx.Commit()
+}
+
+// Sell gives quantity items of the character to the campaign shop, returning the price received
+func (db *DB) Sell(campaignID uint, characterID uint, item string, quantity int) (int, error) {
+	tx := db.begin()
+	defer tx.Rollback()
+
+	shopItem, err := tx.fetchShopItem(campaignID, item)
+	if err != nil {
+		return 0, err
+	}
+	price := shopItem.SellPrice() * quantity
+
+	if err := tx.RemoveItem(characterID, item, quantity); err != nil {
+		return 0, err
+	}
+
+	if shopItem.Stock != UnlimitedStock {
+		if err := tx.Model(&ShopItem{}).
+			Where("id = ?", shopItem.ID).
+			Update("stock", gorm.Expr("stock + ?", quantity)).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.AddGold(characterID, price); err != nil {
+		return 0, err
+	}
+
+	return price, tx.Commit()
+}
+
+// AddGold changes the gold of a character, the gold cannot become negative.
+// The gold is only written by this increment: a character saved with UpdateCharacter keeps the gold of the DB.
+func (db *DB) AddGold(characterID uint, amount int) error {
+	result := db.Model(&Character{}).
+		Where("id = ? AND gold + ? >= 0", characterID, amount).
+		Update("gold", gorm.Expr("gold + ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotEnoughGold
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"time"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+)
+
+// Store is the persistence layer of the game.
+// Begin opens a transaction: the returned Store must be committed or rolled back.
+type Store interface {
+	Begin() Store
+	Commit() error
+	Rollback() error
+
+	FetchCampaign(guildID string) (Campaign, error)
+	FetchOrCreateCampaign(guildID string, gameMaster uint) (Campaign, error)
+	AdoptLegacyGame(guildID string, adventureChannel string, gameMaster uint, now time.Time) error
+	FetchLatestCampaign(userID uint) (Campaign, error)
+	SaveCampaign(c *Campaign) error
+
+	FetchPermissions(campaignID uint) ([]Permission, error)
+	Grant(p *Permission) error
+	Revoke(campaignID uint, userID uint, roleID string) error
+
+	FetchPlayer(userID uint) (Player, error)
+	SavePlayer(p *Player) error
+
+	FetchAdventure(campaignID uint) (Adventure, error)
+	SaveAdventure(a *Adventure) error
+
+	FetchCharacters(campaignID uint) ([]Character, error)
+	FetchCharacterInfo(campaignID uint, userID uint) (Character, error)
+	CreateCharacter(curve *progression.Curve, campaignID uint, userID uint, class string) error
+	ChooseClass(campaignID uint, userID uint, class string) error
+	SaveCharacter(c *Character) error
+	UpdateCharacter(c *Character, columns ...string) error
+	AddGold(characterID uint, amount int) error
+	UpStats(curve *progression.Curve, statsToUp string, campaignID uint, userID uint, amount int) error
+
+	FetchMonsterInfo(campaignID uint) (Monster, error)
+	FetchFoughtMonster(campaignID uint, characterID uint) (Monster, error)
+	FetchMonsters(campaignID uint) ([]Monster, error)
+	FetchMonster(campaignID uint, id uint) (Monster, error)
+	FetchLastDefeatedMonster(campaignID uint) (Monster, error)
+	SpawnMonster(m *Monster) error
+	UpdateMonsterHP(m *Monster) error
+
+	FetchInventory(characterID uint) ([]InventoryItem, error)
+	AddItem(characterID uint, item string, quantity int) error
+	RemoveItem(characterID uint, item string, quantity int) error
+	SetEquipped(characterID uint, item string, equipped bool) error
+
+	FetchShop(campaignID uint) ([]ShopItem, error)
+	StockItem(campaignID uint, item string, price int, stock int) error
+	UnstockItem(campaignID uint, item string) error
+	Buy(campaignID uint, characterID uint, item string, quantity int) (int, error)
+	Sell(campaignID uint, characterID uint, item string, quantity int) (int, error)
+
+	RecordRoll(m *Monster, r BattleRoll) error
+	FetchRolls(monsterID uint) ([]BattleRoll, error)
+	RecordEvent(m *Monster, e BattleEvent) error
+	FetchEvents(monsterID uint) ([]BattleEvent, error)
+	FetchFoughtMonsters(campaignID uint, limit int) ([]Monster, error)
+
+	GetParticipants(campaignID uint, userID uint, target string) (*Character, *Monster, error)
+	AddParticipant(m *Monster, c *Character, damage int) error
+	FetchParticipants(m *Monster) ([]Character, error)
+	FetchParticipations(m *Monster) ([]BattleParticipation, error)
+}
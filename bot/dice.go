@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+)
+
+// dice generates the battle seeds. Every roll of a battle is derived from
+// the battle seed and the roll index, so a fight can be replayed from its seed.
+type dice struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func newDice(seed int64) *dice {
+	return &dice{r: rand.New(rand.NewSource(seed))} //nolint:gosec
+}
+
+// NewSeed returns the seed of a new battle
+func (d *dice) NewSeed() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.r.Int63()
+}
+
+// rollDie returns the index-th roll in [0,sides) of the battle seeded with seed, 0 without sides
+func rollDie(seed int64, index int, sides int) int {
+	if sides < 1 {
+		return 0
+	}
+	return rand.New(rand.NewSource(seed + int64(index))).Intn(sides) //nolint:gosec
+}
+
+// roll draws the next roll of the battle against monster and records it.
+// characterID is the rolling character, 0 for the monster.
+func (b *Bot) roll(tx db.Store, monster *db.Monster, characterID uint, sides int) (int, error) {
+	result := rollDie(monster.Seed, monster.Rolls, sides)
+
+	if err := tx.RecordRoll(monster, db.BattleRoll{
+		CharacterID: characterID,
+		Sides:       sides,
+		Result:      result,
+	}); err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
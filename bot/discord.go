@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// discordTransport sends responses through a Discord session
+type discordTransport struct {
+	s *discordgo.Session
+}
+
+func (t discordTransport) Send(channelID string, message string) error {
+	_, err := t.s.ChannelMessageSend(channelID, message)
+	return err
+}
+
+// SendEmbed sends an embed, channel messages cannot be private
+func (t discordTransport) SendEmbed(channelID string, embed *discordgo.MessageEmbed, private bool) error {
+	_, err := t.s.ChannelMessageSendEmbed(channelID, embed)
+	return err
+}
+
+// ImportLegacyGame moves the game played before campaigns, with the adventure channel saved in
+// current_channel.txt, into the campaign of the server owning that channel
+func (b *Bot) ImportLegacyGame(s *discordgo.Session) error {
+	channelID, err := util.GetChannelID()
+	if err != nil || channelID == "" {
+		return err
+	}
+
+	channel, err := s.Channel(channelID)
+	if err != nil {
+		return err
+	}
+
+	return b.db.AdoptLegacyGame(channel.GuildID, channelID, b.Config.GameMaster, b.now())
+}
+
+// Handler for discord events
+func (b *Bot) Handler(s *discordgo.Session, m *discordgo.MessageCreate) {
+	// Ignore all messages created by the bot itself
+	if m.Author.ID == s.State.User.ID {
+		return
+	}
+
+	authorID64, err := strconv.ParseUint(strings.TrimSpace(m.Author.ID), 10, 64)
+	if err != nil {
+		log.Warn().Msg("[Response] Unexpected error (authorID not an integer)")
+		if _, e := s.ChannelMessageSend(m.ChannelID, b.messages.Translator().T("error.unexpected")); e != nil {
+			log.Error().Err(e).Msg("cannot push message")
+		}
+		return
+	}
+
+	req := NewRequest(m.Content, uint(authorID64), m.Author.Username, m.ChannelID)
+	req.GuildID = m.GuildID
+	req.BotID = s.State.User.ID
+	req.AuthorAvatar = m.Author.AvatarURL("")
+	if m.Member != nil {
+		req.RoleIDs = m.Member.Roles
+	}
+
+	b.Dispatch(discordTransport{s: s}, req)
+}
+
+// interactionTransport answers a slash command, then follows up.
+// Messages for other channels, like the announces of the adventure channel, are sent as usual.
+type interactionTransport struct {
+	s         *discordgo.Session
+	i         *discordgo.Interaction
+	responded bool
+}
+
+func (t *interactionTransport) Send(channelID string, message string) error {
+	return t.send(channelID, message, nil, false)
+}
+
+func (t *interactionTransport) SendPrivate(channelID string, message string) error {
+	return t.send(channelID, message, nil, true)
+}
+
+func (t *interactionTransport) SendEmbed(channelID string, embed *discordgo.MessageEmbed, private bool) error {
+	return t.send(channelID, "", embed, private)
+}
+
+func (t *interactionTransport) send(channelID string, message string, embed *discordgo.MessageEmbed, private bool) error {
+	if channelID != t.i.ChannelID {
+		if embed != nil {
+			return discordTransport{s: t.s}.SendEmbed(channelID, embed, false)
+		}
+		return discordTransport{s: t.s}.Send(channelID, message)
+	}
+
+	var (
+		flags  uint64
+		embeds []*discordgo.MessageEmbed
+	)
+	if private {
+		flags = uint64(discordgo.MessageFlagsEphemeral)
+	}
+	if embed != nil {
+		embeds = []*discordgo.MessageEmbed{embed}
+	}
+
+	if t.responded {
+		_, err := t.s.FollowupMessageCreate(t.i, true, &discordgo.WebhookParams{Content: message, Embeds: embeds, Flags: flags})
+		return err
+	}
+
+	t.responded = true
+	return t.s.InteractionRespond(t.i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: message, Embeds: embeds, Flags: flags},
+	})
+}
+
+// InteractionHandler runs the slash commands with the handlers of the chat commands
+func (b *Bot) InteractionHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	// the member on a guild, the user in direct messages
+	user, roles := i.User, []string(nil)
+	if i.Member != nil {
+		user, roles = i.Member.User, i.Member.Roles
+	}
+	if user == nil {
+		return
+	}
+
+	authorID64, err := strconv.ParseUint(user.ID, 10, 64)
+	if err != nil {
+		log.Warn().Msg("[Response] Unexpected error (authorID not an integer)")
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	cmd, ok := router[data.Name]
+	if !ok {
+		log.Warn().Str("cmd", data.Name).Msg("unknown slash command, the definitions are out of date")
+		return
+	}
+
+	req := NewRequest("/"+data.Name, uint(authorID64), user.Username, i.ChannelID)
+	req.GuildID = i.GuildID
+	req.RoleIDs = roles
+	req.AuthorAvatar = user.AvatarURL("")
+	req.Command = data.Name
+	req.Options = map[string]string{}
+	for _, option := range data.Options {
+		req.Options[option.Name] = slashValue(option)
+	}
+	req.Text = slashText(cmd, req.Options)
+	req.Args = strings.Fields(req.Text)
+
+	t := &interactionTransport{s: s, i: i.Interaction}
+	b.Dispatch(t, req)
+
+	// Discord reports a failure when a slash command gets no answer
+	if !t.responded {
+		if err := t.SendPrivate(i.ChannelID, ":ok_hand:"); err != nil {
+			log.Error().Err(err).Msg("cannot push message")
+		}
+	}
+}
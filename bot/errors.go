@@ -3,7 +3,15 @@ package bot
 import "errors"
 
 var (
-	errNotGameMaster         = errors.New("you are not the game master")
+	errNotGameMaster         = errors.New("you are not a game master")
+	errNotModerator          = errors.New("you are not a moderator")
 	errCharacterDoesNotExist = errors.New("character doesn't exist")
 	errIllegalArgument       = errors.New("illegal argument")
+	errKnockedOut            = errors.New("character is knocked out")
+	errFightInProgress       = errors.New("a fight is in progress")
+	errNotEnoughStamina      = errors.New("not enough stamina")
+	errWrongClass            = errors.New("wrong class for this action")
+	errUnknownClass          = errors.New("unknown class")
+	errRespecCooldown        = errors.New("respec cooling down")
+	errRespecGold            = errors.New("not enough gold to respec")
 )
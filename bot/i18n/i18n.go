@@ -0,0 +1,217 @@
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const localeExt = ".json"
+
+// ErrNoLocale is returned when the catalog directory has no locale file
+var ErrNoLocale = errors.New("no locale")
+
+// Vars are the named values of a message, {{.Name}} in the templates
+type Vars map[string]interface{}
+
+// pluralRules pick the "one" form of a message along the count, "other" otherwise
+var pluralRules = map[string]func(n int) bool{ //nolint:gochecknoglobals
+	"fr": func(n int) bool { return n == 0 || n == 1 },
+	"en": func(n int) bool { return n == 1 },
+}
+
+// isOne tells if count takes the "one" form in the locale, like in English by default
+func isOne(locale string, count int) bool {
+	if rule, ok := pluralRules[locale]; ok {
+		return rule(count)
+	}
+	return count == 1
+}
+
+// message is a template, with a plural form when it depends on a count
+type message struct {
+	one   *template.Template
+	other *template.Template
+}
+
+// pluralForms is a message varying with {{.Count}}
+type pluralForms struct {
+	One   string
+	Other string
+}
+
+// Catalog holds the messages of every locale, read from a directory of JSON files named after
+// the locale (fr.json, en.json...). A message is a template, or the plural forms {"one": ..., "other": ...}.
+type Catalog struct {
+	fallback string
+	locales  map[string]map[string]message
+}
+
+// Load reads the locales of dir. Messages missing from a locale are taken from the fallback locale.
+func Load(dir string, fallback string) (*Catalog, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+localeExt))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{fallback: fallback, locales: map[string]map[string]message{}}
+	for _, file := range files {
+		locale := strings.TrimSuffix(filepath.Base(file), localeExt)
+
+		messages, err := loadLocale(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		c.locales[locale] = messages
+	}
+
+	if !c.Has(fallback) {
+		return nil, fmt.Errorf("%q in %s: %w", fallback, dir, ErrNoLocale)
+	}
+	return c, nil
+}
+
+func loadLocale(file string) (map[string]message, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+
+	messages := map[string]message{}
+	for key, value := range raw {
+		forms := pluralForms{}
+		if err := json.Unmarshal(value, &forms.Other); err != nil {
+			if err := json.Unmarshal(value, &forms); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		if forms.One == "" {
+			forms.One = forms.Other
+		}
+
+		m := message{}
+		if m.one, err = template.New(key).Parse(forms.One); err != nil {
+			return nil, err
+		}
+		if m.other, err = template.New(key).Parse(forms.Other); err != nil {
+			return nil, err
+		}
+		messages[key] = m
+	}
+	return messages, nil
+}
+
+// Has tells if the catalog knows the locale
+func (c *Catalog) Has(locale string) bool {
+	_, ok := c.locales[locale]
+	return ok
+}
+
+// Locales lists the known locales, sorted
+func (c *Catalog) Locales() []string {
+	locales := make([]string, 0, len(c.locales))
+	for locale := range c.locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Keys lists the messages of a locale, sorted
+func (c *Catalog) Keys(locale string) []string {
+	keys := make([]string, 0, len(c.locales[locale]))
+	for key := range c.locales[locale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Translator writes the messages in the first known locale, or in the fallback locale
+func (c *Catalog) Translator(locales ...string) Translator {
+	for _, locale := range locales {
+		if c.Has(locale) {
+			return Translator{catalog: c, locale: locale}
+		}
+	}
+	return Translator{catalog: c, locale: c.fallback}
+}
+
+// Translator writes the messages of a locale
+type Translator struct {
+	catalog *Catalog
+	locale  string
+}
+
+// Locale returns the locale of the messages
+func (t Translator) Locale() string {
+	return t.locale
+}
+
+// Has tells if the message is known, in the locale or in the fallback locale
+func (t Translator) Has(key string) bool {
+	if t.catalog == nil {
+		return false
+	}
+	if _, ok := t.catalog.locales[t.locale][key]; ok {
+		return true
+	}
+	_, ok := t.catalog.locales[t.catalog.fallback][key]
+	return ok
+}
+
+// T writes a message. An unknown message is written as its key.
+func (t Translator) T(key string, vars ...Vars) string {
+	return t.write(key, nil, vars)
+}
+
+// N writes a message varying with count, given to the template as {{.Count}}
+func (t Translator) N(key string, count int, vars ...Vars) string {
+	return t.write(key, &count, vars)
+}
+
+func (t Translator) write(key string, count *int, vars []Vars) string {
+	if t.catalog == nil {
+		return key
+	}
+
+	locale := t.locale
+	m, ok := t.catalog.locales[locale][key]
+	if !ok {
+		locale = t.catalog.fallback
+		if m, ok = t.catalog.locales[locale][key]; !ok {
+			return key
+		}
+	}
+
+	data := Vars{}
+	for _, v := range vars {
+		for name, value := range v {
+			data[name] = value
+		}
+	}
+
+	tmpl := m.other
+	if count != nil {
+		data["Count"] = *count
+		if isOne(locale, *count) {
+			tmpl = m.one
+		}
+	}
+
+	str := strings.Builder{}
+	if err := tmpl.Execute(&str, data); err != nil {
+		return key
+	}
+	return str.String()
+}
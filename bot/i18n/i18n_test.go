@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func writeLocale(t *testing.T, dir string, locale string, content string) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, locale+localeExt), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	dir := t.TempDir()
+	writeLocale(t, dir, "fr", `{
+  "hello": "Bonjour {{.Player}} !",
+  "points": {"one": "{{.Count}} point", "other": "{{.Count}} points"},
+  "only.fr": "Seulement en français"
+}`)
+	writeLocale(t, dir, "en", `{
+  "hello": "Hello {{.Player}}!",
+  "points": {"one": "{{.Count}} point", "other": "{{.Count}} points"}
+}`)
+
+	c, err := Load(dir, "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locales := c.Locales(); len(locales) != 2 || locales[0] != "en" || locales[1] != "fr" {
+		t.Errorf("unexpected locales %v", locales)
+	}
+
+	fr, en := c.Translator("fr"), c.Translator("de", "en")
+	if en.Locale() != "en" || c.Translator("de").Locale() != "fr" {
+		t.Errorf("unexpected locales %q and %q", en.Locale(), c.Translator("de").Locale())
+	}
+
+	cases := []struct {
+		got  string
+		want string
+	}{
+		{fr.T("hello", Vars{"Player": "Bob"}), "Bonjour Bob !"},
+		{en.T("hello", Vars{"Player": "Bob"}), "Hello Bob!"},
+		// French counts zero as singular, English as plural
+		{fr.N("points", 0), "0 point"},
+		{en.N("points", 0), "0 points"},
+		{fr.N("points", 1), "1 point"},
+		{en.N("points", 2), "2 points"},
+		// missing messages come from the fallback locale, or are written as their key
+		{en.T("only.fr"), "Seulement en français"},
+		{en.T("unknown"), "unknown"},
+		{Translator{}.T("hello"), "hello"},
+	}
+	if !en.Has("only.fr") || en.Has("unknown") || (Translator{}).Has("hello") {
+		t.Error("unexpected known messages")
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("expected %q, got %q", c.want, c.got)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir, "fr"); !errors.Is(err, ErrNoLocale) {
+		t.Errorf("expected a missing locale error, got %v", err)
+	}
+
+	writeLocale(t, dir, "fr", `{"hello": "Bonjour {{.Player"}`)
+	if _, err := Load(dir, "fr"); err == nil {
+		t.Error("expected a template error")
+	}
+
+	writeLocale(t, dir, "fr", `{"hello": 12}`)
+	if _, err := Load(dir, "fr"); err == nil {
+		t.Error("expected a format error")
+	}
+}
@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/bestiary"
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/items"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// equipmentBonus sums the attack and the defense of the items equipped by a character
+func (b *Bot) equipmentBonus(tx db.Store, characterID uint) (attack int, defense int, err error) {
+	inventory, err := tx.FetchInventory(characterID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range inventory {
+		if !inventory[i].Equipped {
+			continue
+		}
+
+		item, err := b.items.Get(inventory[i].Item)
+		if err != nil {
+			continue
+		}
+		attack += item.Attack
+		defense += item.Defense
+	}
+	return attack, defense, nil
+}
+
+// equipItem equips an item, replacing the equipped item of the same kind
+func (b *Bot) equipItem(characterID uint, item *items.Item) error {
+	tx := b.db.Begin()
+	defer tx.Rollback()
+
+	inventory, err := tx.FetchInventory(characterID)
+	if err != nil {
+		return err
+	}
+
+	owned := false
+	for i := range inventory {
+		stack := &inventory[i]
+		if stack.Item == item.Key {
+			owned = true
+			continue
+		}
+
+		if other, err := b.items.Get(stack.Item); err == nil && stack.Equipped && other.Kind == item.Kind {
+			if err := tx.SetEquipped(characterID, stack.Item, false); err != nil {
+				return err
+			}
+		}
+	}
+	if !owned {
+		return db.ErrItemNotOwned
+	}
+
+	if err := tx.SetEquipped(characterID, item.Key, true); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// useItem consumes an item, restoring HP and stamina
+func (b *Bot) useItem(campaignID uint, userID uint, item *items.Item) (db.Character, error) {
+	tx := b.db.Begin()
+	defer tx.Rollback()
+
+	character, err := tx.FetchCharacterInfo(campaignID, userID)
+	if err != nil {
+		return character, err
+	}
+
+	if character.IsKnockedOut() {
+		return character, errKnockedOut
+	}
+
+	if err := tx.RemoveItem(character.ID, item.Key, 1); err != nil {
+		return character, err
+	}
+
+	character.Regenerate(b.progression, b.now())
+	character.CurrentHp += item.Heal
+	if character.CurrentHp > character.GetMaxHP(b.progression) {
+		character.CurrentHp = character.GetMaxHP(b.progression)
+	}
+	character.Stamina += item.Stamina
+	if character.Stamina > db.MaxStamina {
+		character.Stamina = db.MaxStamina
+	}
+
+	if err := tx.UpdateCharacter(&character, db.RegenColumns...); err != nil {
+		return character, err
+	}
+
+	return character, tx.Commit()
+}
+
+// distributeLoot rolls the loot table of the monster, every drop goes to a participant drawn along the weights
+func (b *Bot) distributeLoot(tx db.Store, tr i18n.Translator, monster *db.Monster, participants []db.Character,
+	weights []int) (string, error) {
+	if monster.Template == "" || len(participants) == 0 {
+		return "", nil
+	}
+
+	template, err := b.bestiary.Get(monster.Template)
+	if errors.Is(err, bestiary.ErrUnknownTemplate) {
+		log.Warn().Str("template", monster.Template).Msg("no loot table: template removed from the bestiary")
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	report := ""
+	for _, loot := range template.Loot {
+		chance, err := b.roll(tx, monster, 0, 100)
+		if err != nil {
+			return "", err
+		}
+		if float64(chance) >= loot.Chance*100 {
+			continue
+		}
+
+		index, err := b.drawWinner(tx, monster, weights)
+		if err != nil {
+			return "", err
+		}
+		winner := &participants[index]
+
+		quantity := loot.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+		if err := tx.AddItem(winner.ID, loot.Item, quantity); err != nil {
+			return "", err
+		}
+
+		report += tr.T("loot.drop", i18n.Vars{
+			"Quantity": quantity,
+			"Item":     b.items.Name(loot.Item),
+			"Player":   util.DiscordIDToText(winner.UserID),
+		}) + "\n"
+	}
+
+	return report, nil
+}
+
+func (b *Bot) writeInventory(tr i18n.Translator, userID uint, inventory []db.InventoryItem) string {
+	player := i18n.Vars{"Player": util.DiscordIDToText(userID)}
+	if len(inventory) == 0 {
+		return tr.T("inventory.empty", player)
+	}
+
+	str := tr.T("inventory.title", player) + "\n"
+	for i := range inventory {
+		stack := &inventory[i]
+		str += tr.T("inventory.item", i18n.Vars{"Item": b.items.Name(stack.Item), "Quantity": stack.Quantity})
+		if stack.Equipped {
+			str += tr.T("inventory.equipped")
+		}
+
+		if item, err := b.items.Get(stack.Item); err == nil {
+			str += writeItemEffects(tr, &item)
+		}
+		str += "\n"
+	}
+	return str
+}
+
+func writeItemEffects(tr i18n.Translator, item *items.Item) string {
+	effects := []string{}
+	for _, effect := range []struct {
+		value int
+		key   string
+	}{
+		{item.Attack, "item.attack"},
+		{item.Defense, "item.defense"},
+		{item.Heal, "item.heal"},
+		{item.Stamina, "item.stamina"},
+	} {
+		if effect.value != 0 {
+			effects = append(effects, tr.T(effect.key, i18n.Vars{"Value": effect.value}))
+		}
+	}
+
+	if len(effects) == 0 {
+		return ""
+	}
+	return tr.T("item.effects", i18n.Vars{"Effects": strings.Join(effects, " ")})
+}
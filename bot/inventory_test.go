@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+const ratTemplate = `{
+  "Name": "Rat",
+  "Experience": 10,
+  "Strength": 1,
+  "Agility": 0,
+  "Constitution": -9,
+  "Loot": [
+    {"Item": "potion", "Chance": 1, "Quantity": 2},
+    {"Item": "sword", "Chance": 0, "Quantity": 1}
+  ]
+}`
+
+func TestLootOnVictory(t *testing.T) {
+	h := newHarness(t)
+	h.addTemplate("rat", ratTemplate)
+	h.sayOne(10, "!join_adventure")
+
+	expectContains(t, h.sayOne(10, "!inventory"), "<@10> n'a rien dans son sac.")
+
+	h.sayOne(testGameMaster, "!spawn rat")
+	report := h.sayOne(10, "!hit")
+	expectContains(t, report, "L'adversaire est vaincu !")
+	expectContains(t, report, "Butin : 2x Potion pour <@10>\n")
+
+	expectContains(t, h.sayOne(10, "!inventory"), "- Potion x2 : +5 HP +20 endurance\n")
+}
+
+func TestEquipment(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	for _, item := range []string{"sword", "club", "shield"} {
+		if err := h.store.AddItem(h.character(10).ID, item, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectContains(t, h.sayOne(10, "!equip"), "Mauvaise syntaxe (objet manquant), essayez `!equip dagger`")
+	expectContains(t, h.sayOne(10, "!equip bow"), "Objet inconnu : bow")
+	expectContains(t, h.sayOne(10, "!equip potion"), "Potion ne s'équipe pas.")
+	expectContains(t, h.sayOne(11, "!equip sword"), "rejoindre l'aventure")
+
+	expectContains(t, h.sayOne(10, "!equip club"), "<@10> s'équipe : Club.")
+	expectContains(t, h.sayOne(10, "!equip Sword"), "<@10> s'équipe : Sword.")
+	expectContains(t, h.sayOne(10, "!equip shield"), "<@10> s'équipe : Shield.")
+
+	inventory := h.sayOne(10, "!inventory")
+	expectContains(t, inventory, "- Club x1 : +1 attaque\n")
+	expectContains(t, inventory, "- Sword x1 (équipé) : +3 attaque\n")
+	expectContains(t, inventory, "- Shield x1 (équipé) : +100 défense\n")
+
+	h.sayOne(testGameMaster, "!spawn Dummy_0_10_0_1_1000")
+	report := h.sayOne(10, "!hit")
+	expectContains(t, report, "**<@10>** inflige ")
+	expectContains(t, report, " (1+3+")
+	expectContains(t, report, "inflige 1 (10+0-1-100) points de dégâts à **<@10>**")
+
+	expectContains(t, h.sayOne(10, "!unequip sword"), "<@10> retire : Sword.")
+	report = h.sayOne(10, "!hit")
+	expectContains(t, report, "**<@10>** inflige ")
+	if strings.Contains(report, "(1+3+") {
+		t.Errorf("the sword is still equipped: %q", report)
+	}
+}
+
+func TestUseAndDropItems(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	if err := h.store.AddItem(h.character(10).ID, "potion", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	c := h.character(10)
+	c.CurrentHp = 2
+	c.Stamina = 50
+	if err := h.store.SaveCharacter(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	expectContains(t, h.sayOne(10, "!use sword"), "Sword ne s'utilise pas.")
+	expectContains(t, h.sayOne(10, "!use potion"), "<@10> utilise : Potion (7 / 12 HP, 70 / 100 d'endurance).")
+
+	expectContains(t, h.sayOne(10, "!drop potion 0"), "quantité doit être au moins 1")
+	expectContains(t, h.sayOne(10, "!drop potion 3"), "Vous n'avez pas assez de Potion.")
+	expectContains(t, h.sayOne(10, "!drop Potion 2"), "<@10> jette 2x Potion.")
+	expectContains(t, h.sayOne(10, "!inventory"), "<@10> n'a rien dans son sac.")
+	expectContains(t, h.sayOne(10, "!use potion"), "Vous n'avez pas de Potion.")
+}
@@ -0,0 +1,111 @@
+package items
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownItem is returned when no item matches a key or a name
+var ErrUnknownItem = errors.New("unknown item")
+
+// Kind of item, telling how it is used
+type Kind string
+
+const (
+	// Weapon adds its Attack to the physical attacks, when equipped
+	Weapon Kind = "weapon"
+	// Armor adds its Defense to the damage reduction, when equipped
+	Armor Kind = "armor"
+	// Consumable restores Heal HP and Stamina when used, then disappears
+	Consumable Kind = "consumable"
+)
+
+// Item is the definition of an item
+type Item struct {
+	// Key identifies the item in the inventories and the loot tables
+	Key         string `json:"-"`
+	Name        string
+	Description string
+	Kind        Kind
+	Attack      int
+	Defense     int
+	Heal        int
+	Stamina     int
+}
+
+// Equipable tells if the item can be equipped
+func (i Item) Equipable() bool {
+	return i.Kind == Weapon || i.Kind == Armor
+}
+
+// Catalog is the list of the items of the game, loaded from a JSON file mapping keys to items
+type Catalog struct {
+	items map[string]Item
+}
+
+// Load reads the catalog file. A missing file is an empty catalog.
+func Load(file string) (*Catalog, error) {
+	c := &Catalog{items: map[string]Item{}}
+
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &c.items); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", file, err)
+	}
+
+	for key := range c.items {
+		item := c.items[key]
+		item.Key = key
+		if item.Name == "" {
+			item.Name = key
+		}
+		c.items[key] = item
+	}
+	return c, nil
+}
+
+// Get returns the item matching a key or a name, case insensitive
+func (c *Catalog) Get(keyOrName string) (Item, error) {
+	keyOrName = strings.TrimSpace(keyOrName)
+
+	if item, ok := c.items[strings.ToLower(keyOrName)]; ok {
+		return item, nil
+	}
+
+	for key := range c.items {
+		if strings.EqualFold(c.items[key].Name, keyOrName) {
+			return c.items[key], nil
+		}
+	}
+
+	return Item{}, fmt.Errorf("%q: %w", keyOrName, ErrUnknownItem)
+}
+
+// Name returns the name of the item of key, or the key itself for an unknown item
+func (c *Catalog) Name(key string) string {
+	if item, ok := c.items[key]; ok {
+		return item.Name
+	}
+	return key
+}
+
+// List returns every item, sorted by key
+func (c *Catalog) List() []Item {
+	list := make([]Item, 0, len(c.items))
+	for key := range c.items {
+		list = append(list, c.items[key])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
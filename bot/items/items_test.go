@@ -0,0 +1,52 @@
+package items
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "items.json")
+	content := `{"potion": {"Name": "Potion de soin", "Kind": "consumable", "Heal": 10}, "stick": {"Kind": "weapon"}}`
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"potion", "POTION", "potion de soin"} {
+		item, err := c.Get(name)
+		if err != nil || item.Key != "potion" || item.Heal != 10 || item.Equipable() {
+			t.Errorf("Get(%q) = %+v, %v", name, item, err)
+		}
+	}
+
+	if item, err := c.Get("stick"); err != nil || item.Name != "stick" || !item.Equipable() {
+		t.Errorf("unexpected stick %+v, %v", item, err)
+	}
+
+	if _, err := c.Get("bow"); !errors.Is(err, ErrUnknownItem) {
+		t.Errorf("expected an unknown item, got %v", err)
+	}
+	if c.Name("bow") != "bow" {
+		t.Error("expected the key as the name of an unknown item")
+	}
+	if len(c.List()) != 2 {
+		t.Errorf("unexpected list %+v", c.List())
+	}
+}
+
+func TestShippedCatalog(t *testing.T) {
+	c, err := Load(filepath.Join("..", "..", "items.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.List()) == 0 {
+		t.Error("expected items in the shipped catalog")
+	}
+}
@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// authorize restricts a command to the users granted its permission, or a higher role
+func authorize(req *Request, cmd *_Command, role db.Role) *_Response {
+	if role.Includes(cmd.Permission) {
+		return nil
+	}
+
+	resp := simpleErr(errNotModerator, req.T("permission.not_moderator"))
+	if cmd.Permission == db.RoleGameMaster {
+		resp = simpleErr(errNotGameMaster, req.T("permission.not_game_master"))
+	}
+	return &resp
+}
+
+// role returns the highest role of the author in the campaign. The bot owner is a game master everywhere.
+func (b *Bot) role(req *Request) (db.Role, error) {
+	if req.AuthorID == b.Config.GameMaster {
+		return db.RoleGameMaster, nil
+	}
+
+	permissions, err := b.db.FetchPermissions(req.Campaign.ID)
+	if err != nil {
+		return db.RolePlayer, err
+	}
+
+	role := db.RolePlayer
+	for i := range permissions {
+		p := &permissions[i]
+		if !p.Role.Includes(role) {
+			continue
+		}
+		if (p.UserID != 0 && p.UserID == req.AuthorID) || (p.RoleID != "" && hasDiscordRole(req, p.RoleID)) {
+			role = p.Role
+		}
+	}
+	return role, nil
+}
+
+func hasDiscordRole(req *Request, roleID string) bool {
+	for _, id := range req.RoleIDs {
+		if id == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// gmCmd manages who runs the campaign: !gm add|remove|list
+func (b *Bot) gmCmd(req *Request) _Response {
+	action, target := req.args.String("action"), req.args.String("@player|@role")
+	if action == "list" {
+		if target != "" {
+			return badSyntax(fmt.Errorf("gm list: %w", &argError{reason: "too many"}))
+		}
+		return b.listPermissions(req)
+	}
+
+	p, ok := parsePermissionTarget(target)
+	if !ok {
+		return badSyntax(fmt.Errorf("gm %s: %w", action, &argError{arg: "@player|@role", reason: "mention"}))
+	}
+
+	if action == "remove" {
+		if req.args.Has("gm|moderator") {
+			return badSyntax(fmt.Errorf("gm remove: %w", &argError{reason: "too many"}))
+		}
+		if err := b.db.Revoke(req.Campaign.ID, p.UserID, p.RoleID); err != nil {
+			if errors.Is(err, db.ErrNoPermission) {
+				return simpleErr(err, req.T("gm.no_role", i18n.Vars{"Target": writePermissionTarget(&p)}))
+			}
+			return simpleErr(fmt.Errorf("cannot revoke role: %w", err), req.T("gm.revoke_error"))
+		}
+		return simpleResponse(req.T("gm.revoked", i18n.Vars{"Target": writePermissionTarget(&p)}))
+	}
+
+	role := db.RoleGameMaster
+	if name := req.args.String("gm|moderator"); name != "" {
+		r, ok := db.ParseRole(name)
+		if !ok || r == db.RolePlayer {
+			return simpleErr(fmt.Errorf("role %q: %w", name, errIllegalArgument), req.T("gm.unknown_role"))
+		}
+		role = r
+	}
+
+	p.CampaignID = req.Campaign.ID
+	p.Role = role
+	if err := b.db.Grant(&p); err != nil {
+		return simpleErr(fmt.Errorf("cannot grant role: %w", err), req.T("gm.grant_error"))
+	}
+	return simpleResponse(req.T("gm.granted", i18n.Vars{
+		"Target": writePermissionTarget(&p),
+		"Role":   req.T("role." + string(role)),
+	}))
+}
+
+func (b *Bot) listPermissions(req *Request) _Response {
+	permissions, err := b.db.FetchPermissions(req.Campaign.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch permissions: %w", err), req.T("gm.list_error"))
+	}
+
+	str := req.T("gm.title") + "\n"
+	if b.Config.GameMaster != 0 {
+		str += req.T("gm.entry", i18n.Vars{
+			"Target": util.DiscordIDToText(b.Config.GameMaster),
+			"Role":   req.T("role.owner"),
+		}) + "\n"
+	}
+	for i := range permissions {
+		// the owner is listed once, though the campaign was created with a permission for them
+		if permissions[i].UserID != 0 && permissions[i].UserID == b.Config.GameMaster {
+			continue
+		}
+		str += req.T("gm.entry", i18n.Vars{
+			"Target": writePermissionTarget(&permissions[i]),
+			"Role":   req.T("role." + string(permissions[i].Role)),
+		}) + "\n"
+	}
+	return simpleResponse(str)
+}
+
+// parsePermissionTarget reads a user mention or a Discord role mention
+func parsePermissionTarget(text string) (db.Permission, bool) {
+	if roleID, ok := util.TextToRoleID(text); ok {
+		return db.Permission{RoleID: roleID}, true
+	}
+
+	userID, err := util.TextToDiscordID(text)
+	if err != nil {
+		return db.Permission{}, false
+	}
+	return db.Permission{UserID: userID}, true
+}
+
+func writePermissionTarget(p *db.Permission) string {
+	if p.RoleID != "" {
+		return util.RoleIDToText(p.RoleID)
+	}
+	return util.DiscordIDToText(p.UserID)
+}
@@ -0,0 +1,28 @@
+package bot
+
+import (
+	"testing"
+)
+
+func TestGameMasterManagement(t *testing.T) {
+	h := newHarness(t)
+
+	expectContains(t, h.sayOne(testGameMaster, "!gm"), "Syntax")
+	expectContains(t, h.sayOne(testGameMaster, "!gm add <@10> king"), "Unknown role")
+	expectContains(t, h.sayOne(testGameMaster, "!gm add <@10> moderator"), "<@10> is now moderator")
+	expectContains(t, h.sayOne(testGameMaster, "!gm add <@&55>"), "<@&55> is now gm")
+	expectContains(t, h.sayOne(testGameMaster, "!gm list"), "Roles:\n- <@1>: owner\n- <@10>: moderator\n- <@&55>: gm\n")
+
+	// moderators cannot run the game
+	expectContains(t, h.sayOne(10, "!shout Hello"), "!start_adventure")
+	expectContains(t, h.sayOne(10, "!spawn Rat_10_1_1_1_1"), "Vous n'êtes pas maître du jeu")
+	expectContains(t, h.sayOne(11, "!shout Hello"), "Vous n'êtes pas modérateur")
+
+	// members of a Discord role
+	h.roles = map[uint][]string{11: {"55"}}
+	expectContains(t, h.sayOne(11, "!spawn Rat_10_1_1_1_1"), "Monstre apparu")
+
+	expectContains(t, h.sayOne(testGameMaster, "!gm remove <@&55>"), "<@&55> is now a player")
+	expectContains(t, h.sayOne(testGameMaster, "!gm remove <@&55>"), "<@&55> has no role")
+	expectContains(t, h.sayOne(11, "!spawn Rat_10_1_1_1_1"), "Vous n'êtes pas maître du jeu")
+}
@@ -0,0 +1,149 @@
+package progression
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// ErrInvalidCurve is returned for a progression file the characters could not level up with
+var ErrInvalidCurve = errors.New("invalid progression")
+
+// Curve is how the characters level up, loaded from a JSON file.
+// The experience needed for each level comes from Thresholds when set, from the formula otherwise:
+// reaching level 2 costs FirstLevelXP, and every level after costs LevelXPIncrease more than the previous one.
+type Curve struct {
+	// Thresholds are the total experience needed to reach level 2, 3... The last one is the level cap.
+	Thresholds      []int
+	FirstLevelXP    int
+	LevelXPIncrease int
+	// SkillPointsPerLevel are given to the character at each level up
+	SkillPointsPerLevel int
+	// the max HP of a character is BaseHP + Constitution + HPPerLevel * Level
+	BaseHP     int
+	HPPerLevel int
+	// LevelCap is the highest level, none when 0
+	LevelCap int
+	// a stat cannot go above StatCapBase + StatCapPerLevel * Level, no cap when both are 0
+	StatCapBase     int
+	StatCapPerLevel int
+	// RespecGold is the price of a !respec, RespecCooldownHours the wait between two of them
+	RespecGold          int
+	RespecCooldownHours int
+}
+
+// Default is the curve of the game without progression file:
+// 100 XP to reach level 2, 300 for level 3, 600 for level 4...
+func Default() *Curve {
+	return &Curve{
+		FirstLevelXP:        100,
+		LevelXPIncrease:     100,
+		SkillPointsPerLevel: 5,
+		BaseHP:              10,
+		HPPerLevel:          1,
+		StatCapBase:         5,
+		StatCapPerLevel:     3,
+	}
+}
+
+// Load reads the progression file, the missing fields keep their Default value. A missing file is the Default curve.
+func Load(file string) (*Curve, error) {
+	c := Default()
+
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", file, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return c, nil
+}
+
+func (c *Curve) validate() error {
+	previous := 0
+	for i, xp := range c.Thresholds {
+		if xp <= previous {
+			return fmt.Errorf("threshold of level %d is not above the previous one: %w", i+2, ErrInvalidCurve)
+		}
+		previous = xp
+	}
+	if len(c.Thresholds) == 0 && (c.FirstLevelXP <= 0 || c.LevelXPIncrease < 0) {
+		return fmt.Errorf("FirstLevelXP must be positive and LevelXPIncrease not negative: %w", ErrInvalidCurve)
+	}
+	if c.SkillPointsPerLevel < 0 || c.HPPerLevel < 0 || c.LevelCap < 0 {
+		return fmt.Errorf("negative points per level or level cap: %w", ErrInvalidCurve)
+	}
+	if c.StatCapBase < 0 || c.StatCapPerLevel < 0 || c.RespecGold < 0 || c.RespecCooldownHours < 0 {
+		return fmt.Errorf("negative stat cap or respec cost: %w", ErrInvalidCurve)
+	}
+	return nil
+}
+
+// MaxLevel returns the highest level a character can reach, 0 when there is none
+func (c *Curve) MaxLevel() int {
+	max := c.LevelCap
+	if len(c.Thresholds) > 0 && (max == 0 || len(c.Thresholds)+1 < max) {
+		max = len(c.Thresholds) + 1
+	}
+	return max
+}
+
+// Threshold returns the total experience needed to reach level, false beyond the level cap
+func (c *Curve) Threshold(level int) (int, bool) {
+	if level <= 1 {
+		return 0, true
+	}
+	if max := c.MaxLevel(); max > 0 && level > max {
+		return 0, false
+	}
+	if len(c.Thresholds) > 0 {
+		return c.Thresholds[level-2], true
+	}
+
+	// the sum of the costs of levels 2 to level, growing by LevelXPIncrease
+	n := level - 1
+	return n*c.FirstLevelXP + n*(n-1)/2*c.LevelXPIncrease, true
+}
+
+// Level returns the level reached with experience
+func (c *Curve) Level(experience int) int {
+	level := 1
+	for {
+		xp, ok := c.Threshold(level + 1)
+		if !ok || experience < xp {
+			return level
+		}
+		level++
+	}
+}
+
+// SkillPoints returns the skill points given for levels level ups
+func (c *Curve) SkillPoints(levels int) int {
+	return levels * c.SkillPointsPerLevel
+}
+
+// MaxHP returns the max HP of a character
+func (c *Curve) MaxHP(constitution int, level int) int {
+	return c.BaseHP + constitution + c.HPPerLevel*level
+}
+
+// StatCap returns the highest value of a stat at level, 0 when there is none
+func (c *Curve) StatCap(level int) int {
+	return c.StatCapBase + c.StatCapPerLevel*level
+}
+
+// RespecCooldown returns the wait between two respecs
+func (c *Curve) RespecCooldown() time.Duration {
+	return time.Duration(c.RespecCooldownHours) * time.Hour
+}
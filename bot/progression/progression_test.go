@@ -0,0 +1,102 @@
+package progression
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultLevels(t *testing.T) {
+	cases := []struct {
+		experience int
+		level      int
+	}{
+		{0, 1},
+		{99, 1},
+		{100, 2},
+		{299, 2},
+		{300, 3},
+		{600, 4},
+		{5000, 10},
+	}
+	c := Default()
+	for _, l := range cases {
+		if got := c.Level(l.experience); got != l.level {
+			t.Errorf("Level(%d) = %d, want %d", l.experience, got, l.level)
+		}
+	}
+
+	if c.MaxHP(3, 2) != 15 || c.SkillPoints(2) != 10 || c.MaxLevel() != 0 {
+		t.Errorf("unexpected default curve %+v", c)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "progression.json")
+	content := `{"Thresholds": [50, 150, 400], "HPPerLevel": 3}`
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the table sets the level cap, the missing fields keep their default
+	for xp, level := range map[int]int{0: 1, 49: 1, 50: 2, 399: 3, 400: 4, 9999: 4} {
+		if got := c.Level(xp); got != level {
+			t.Errorf("Level(%d) = %d, want %d", xp, got, level)
+		}
+	}
+	if _, ok := c.Threshold(5); ok || c.MaxLevel() != 4 {
+		t.Errorf("expected level 4 to be the last one, got %d", c.MaxLevel())
+	}
+	if c.MaxHP(1, 2) != 17 || c.SkillPoints(1) != 5 {
+		t.Errorf("unexpected HP or skill points with %+v", c)
+	}
+
+	if c, err := Load(filepath.Join(t.TempDir(), "missing.json")); err != nil || c.Level(100) != 2 {
+		t.Errorf("expected the default curve without file, got %+v, %v", c, err)
+	}
+}
+
+func TestLevelCap(t *testing.T) {
+	c := Default()
+	c.LevelCap = 3
+
+	if c.Level(5000) != 3 {
+		t.Errorf("expected the level to stop at the cap, got %d", c.Level(5000))
+	}
+	if xp, ok := c.Threshold(3); !ok || xp != 300 {
+		t.Errorf("expected 300 XP for the last level, got %d", xp)
+	}
+}
+
+func TestInvalidCurve(t *testing.T) {
+	for _, content := range []string{
+		`{"Thresholds": [100, 100]}`,
+		`{"FirstLevelXP": 0}`,
+		`{"HPPerLevel": -1}`,
+		`{"RespecGold": -10}`,
+	} {
+		file := filepath.Join(t.TempDir(), "progression.json")
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(file); !errors.Is(err, ErrInvalidCurve) {
+			t.Errorf("%s: expected an invalid curve, got %v", content, err)
+		}
+	}
+}
+
+func TestShippedProgression(t *testing.T) {
+	c, err := Load(filepath.Join("..", "..", "progression.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Level(600) != Default().Level(600) {
+		t.Error("expected the shipped progression to keep the default pacing")
+	}
+}
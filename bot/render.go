@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+const barWidth = 10
+
+// blank is the invisible text of the empty fields, laying out the inline fields in rows
+const blank = "\u200b"
+
+// embed colors, along the health of the character or the monster
+const (
+	colorHealthy    = 0x2ecc71
+	colorWounded    = 0xf1c40f
+	colorCritical   = 0xe74c3c
+	colorKnockedOut = 0x95a5a6
+)
+
+// embedTransport renders the embeds, like Discord. Other transports send the plain text of the message.
+type embedTransport interface {
+	SendEmbed(channelID string, embed *discordgo.MessageEmbed, private bool) error
+}
+
+// healthColor picks the color of an embed along the HP left
+func healthColor(hp int, maxHP int) int {
+	switch {
+	case hp <= 0:
+		return colorKnockedOut
+	case 4*hp <= maxHP:
+		return colorCritical
+	case 2*hp <= maxHP:
+		return colorWounded
+	}
+	return colorHealthy
+}
+
+func barField(name string, value int, max int) *discordgo.MessageEmbedField {
+	return &discordgo.MessageEmbedField{
+		Name:  name,
+		Value: util.ProgressBar(value, max, barWidth) + " " + strconv.Itoa(value) + " / " + strconv.Itoa(max),
+	}
+}
+
+func statField(name string, value int) *discordgo.MessageEmbedField {
+	return &discordgo.MessageEmbedField{Name: name, Value: strconv.Itoa(value), Inline: true}
+}
+
+// characterMessage renders a character sheet, avatar is the picture of the player, if known
+func characterMessage(tr i18n.Translator, curve *progression.Curve, c *db.Character, avatar string) _Message {
+	embed := &discordgo.MessageEmbed{
+		Title:       tr.T("sheet.title", i18n.Vars{"Class": className(tr, c.Class), "Level": c.Level}),
+		Description: util.DiscordIDToText(c.UserID),
+		Color:       healthColor(c.CurrentHp, c.GetMaxHP(curve)),
+		Fields: []*discordgo.MessageEmbedField{
+			barField(tr.T("sheet.hp"), c.CurrentHp, c.GetMaxHP(curve)),
+			barField(tr.T("sheet.stamina"), c.Stamina, db.MaxStamina),
+			statField(tr.T("sheet.experience"), c.Experience),
+			statField(tr.T("sheet.gold"), c.Gold),
+			{Name: blank, Value: blank, Inline: true},
+			statField(tr.T("sheet.strength"), c.Strength),
+			statField(tr.T("sheet.agility"), c.Agility),
+			statField(tr.T("sheet.wisdom"), c.Wisdom),
+			statField(tr.T("sheet.constitution"), c.Constitution),
+		},
+	}
+	if c.IsKnockedOut() {
+		embed.Title += " - " + tr.T("sheet.knocked_out")
+	}
+	if avatar != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: avatar}
+	}
+	if c.SkillPoints > 0 {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: tr.N("sheet.skill_points", c.SkillPoints)}
+	}
+
+	return _Message{Message: characterText(tr, curve, c), Embed: embed}
+}
+
+// characterText writes a character sheet as plain text
+func characterText(tr i18n.Translator, curve *progression.Curve, c *db.Character) string {
+	str := util.DiscordIDToText(c.UserID) + " (" + className(tr, c.Class) + ") - " +
+		strconv.Itoa(c.CurrentHp) + " / " + strconv.Itoa(c.GetMaxHP(curve)) + " HP"
+	if c.IsKnockedOut() {
+		str += " - " + tr.T("sheet.knocked_out")
+	}
+	str += "\n" +
+		sheetLine(tr, "sheet.stamina", strconv.Itoa(c.Stamina)+" / "+strconv.Itoa(db.MaxStamina)) +
+		tr.T("sheet.level", i18n.Vars{"Level": c.Level, "Experience": c.Experience}) + "\n" +
+		sheetLine(tr, "sheet.gold", c.Gold) +
+		sheetLine(tr, "sheet.strength", c.Strength) +
+		sheetLine(tr, "sheet.agility", c.Agility) +
+		sheetLine(tr, "sheet.wisdom", c.Wisdom) +
+		sheetLine(tr, "sheet.constitution", c.Constitution)
+
+	if c.SkillPoints > 0 {
+		str += "\n" + tr.N("sheet.skill_points", c.SkillPoints) + "\n"
+	}
+
+	return str
+}
+
+// sheetLine writes a "label : value" line of the plain text sheet
+func sheetLine(tr i18n.Translator, label string, value interface{}) string {
+	return tr.T("sheet.line", i18n.Vars{"Label": tr.T(label), "Value": value}) + "\n"
+}
+
+// monsterMessage renders a monster, image is its picture from the bestiary, if any
+func monsterMessage(tr i18n.Translator, m *db.Monster, image string) _Message {
+	embed := &discordgo.MessageEmbed{
+		Title:  m.Name,
+		Color:  healthColor(m.CurrentHp, m.GetMaxHP()),
+		Fields: []*discordgo.MessageEmbedField{barField(tr.T("sheet.hp"), m.CurrentHp, m.GetMaxHP())},
+	}
+	if image != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: image}
+	}
+
+	return _Message{Message: m.String(), Embed: embed}
+}
+
+// encounterMessage renders the monsters of an encounter, numbered as targets of !hit
+func encounterMessage(tr i18n.Translator, monsters []db.Monster) _Message {
+	embed := &discordgo.MessageEmbed{Title: tr.T("encounter.title"), Color: colorCritical}
+
+	text := ""
+	for i := range monsters {
+		m := &monsters[i]
+
+		text += strconv.Itoa(i+1) + ". " + m.String()
+		embed.Fields = append(embed.Fields, barField(strconv.Itoa(i+1)+". "+m.Name, m.CurrentHp, m.GetMaxHP()))
+	}
+
+	return _Message{Message: text, Embed: embed}
+}
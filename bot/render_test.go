@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+)
+
+// fakeEmbedSession is a Transport rendering the embeds, like Discord
+type fakeEmbedSession struct {
+	*fakeSession
+	embeds []*discordgo.MessageEmbed
+}
+
+func (f *fakeEmbedSession) SendEmbed(channelID string, embed *discordgo.MessageEmbed, private bool) error {
+	f.embeds = append(f.embeds, embed)
+	return nil
+}
+
+func TestCharacterMessage(t *testing.T) {
+	h := newHarness(t)
+	tr := h.bot.messages.Translator()
+	c := db.Character{UserID: 10, Class: db.ClassMage, Level: 2, CurrentHp: 3, Stamina: 40,
+		Constitution: 1, Strength: 2, SkillPoints: 1}
+
+	msg := characterMessage(tr, h.bot.progression, &c, "https://cdn/avatar.png")
+	if msg.Message != characterText(tr, h.bot.progression, &c) {
+		t.Errorf("expected the plain text of the sheet, got %q", msg.Message)
+	}
+
+	embed := msg.Embed
+	if embed.Title != "Mage niveau 2" || embed.Description != "<@10>" || embed.Color != colorCritical {
+		t.Errorf("unexpected embed %+v", embed)
+	}
+	if embed.Thumbnail == nil || embed.Thumbnail.URL != "https://cdn/avatar.png" {
+		t.Errorf("expected the avatar as thumbnail, got %+v", embed.Thumbnail)
+	}
+	expectContains(t, embed.Fields[0].Value, "`███░░░░░░░` 3 / 13")
+	expectContains(t, embed.Fields[1].Value, "`████░░░░░░` 40 / 100")
+	if embed.Footer == nil || embed.Footer.Text != "Il vous reste 1 point à répartir." {
+		t.Errorf("unexpected footer %+v", embed.Footer)
+	}
+
+	c.CurrentHp = 0
+	if embed := characterMessage(tr, h.bot.progression, &c, "").Embed; embed.Color != colorKnockedOut ||
+		!strings.HasSuffix(embed.Title, "K.O.") || embed.Thumbnail != nil {
+		t.Errorf("unexpected knocked out embed %+v", embed)
+	}
+}
+
+func TestMonsterMessages(t *testing.T) {
+	h := newHarness(t)
+	h.addTemplate("goblin", strings.Replace(goblinTemplate, `"Name"`, `"Image": "https://cdn/goblin.png", "Name"`, 1))
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn goblin")
+
+	session := &fakeEmbedSession{fakeSession: &fakeSession{}}
+	dispatch := func(content string) *discordgo.MessageEmbed {
+		req := NewRequest(content, 10, "player", testChannel)
+		req.GuildID = testGuild
+		req.AuthorAvatar = "https://cdn/avatar.png"
+
+		before := len(session.embeds)
+		h.bot.Dispatch(session, req)
+		if len(session.embeds) != before+1 || len(session.sent) != 0 {
+			t.Fatalf("%q: expected an embed, got %d embeds and %q", content, len(session.embeds)-before, session.sent)
+		}
+		return session.embeds[before]
+	}
+
+	embed := dispatch("!watch")
+	if embed.Title != "Goblin" || embed.Color != colorHealthy || embed.Thumbnail.URL != "https://cdn/goblin.png" {
+		t.Errorf("unexpected monster embed %+v", embed)
+	}
+	expectContains(t, embed.Fields[0].Value, "15 / 15")
+
+	if embed := dispatch("!character"); embed.Thumbnail.URL != "https://cdn/avatar.png" {
+		t.Errorf("unexpected character embed %+v", embed)
+	}
+
+	h.sayOne(testGameMaster, "!spawn Rat_10_1_1_1_1")
+	embed = dispatch("!watch")
+	if len(embed.Fields) != 2 || embed.Fields[1].Name != "2. Rat" {
+		t.Errorf("unexpected encounter embed %+v", embed.Fields)
+	}
+
+	// other transports get the plain text
+	expectContains(t, h.sayOne(10, "!watch"), "2. Rat - 11 / 11 HP")
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"sort"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+)
+
+// lastHitBonus is the part of the rewards going to the last blow with db.SplitLastHit, in percent
+const lastHitBonus = 25
+
+// contribution is what a participant did in a fight
+type contribution struct {
+	damage  int
+	lastHit bool
+}
+
+// rewardWeights are the parts of the participants in the rewards, along the split strategy
+func rewardWeights(strategy string, contributions []contribution) []int {
+	weights := make([]int, len(contributions))
+	total := 0
+	for i := range contributions {
+		weights[i] = 1
+		if strategy == db.SplitProportional {
+			weights[i] = contributions[i].damage
+			total += weights[i]
+		}
+	}
+
+	// no damage recorded, like fights started before the damage was tracked: share evenly
+	if strategy == db.SplitProportional && total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	return weights
+}
+
+// rewardShares divides a reward, experience or gold, between the participants along the split strategy
+func rewardShares(strategy string, total int, contributions []contribution) []int {
+	shares := make([]int, len(contributions))
+	pool := total
+
+	if strategy == db.SplitLastHit {
+		for i := range contributions {
+			if contributions[i].lastHit {
+				shares[i] = total * lastHitBonus / 100
+				pool -= shares[i]
+			}
+		}
+	}
+
+	for i, share := range splitAlong(pool, rewardWeights(strategy, contributions), contributions) {
+		shares[i] += share
+	}
+	return shares
+}
+
+// splitAlong divides total along the weights, losing nothing to the integer division:
+// the points left go to the largest fractional parts, the biggest damage dealers first on a tie
+func splitAlong(total int, weights []int, contributions []contribution) []int {
+	sum := 0
+	for _, w := range weights {
+		sum += w
+	}
+
+	shares := make([]int, len(weights))
+	if sum == 0 {
+		return shares
+	}
+
+	remainders := make([]int, len(weights))
+	left := total
+	for i, w := range weights {
+		shares[i] = total * w / sum
+		remainders[i] = total * w % sum
+		left -= shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if remainders[a] != remainders[b] {
+			return remainders[a] > remainders[b]
+		}
+		return contributions[a].damage > contributions[b].damage
+	})
+	for i := 0; i < left; i++ {
+		shares[order[i]]++
+	}
+	return shares
+}
+
+// drawWinner picks a participant along the weights, with a roll of the battle against monster
+func (b *Bot) drawWinner(tx db.Store, monster *db.Monster, weights []int) (int, error) {
+	if len(weights) == 1 {
+		return 0, nil
+	}
+
+	sum := 0
+	for _, w := range weights {
+		sum += w
+	}
+	r, err := b.roll(tx, monster, 0, sum)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, w := range weights {
+		if r < w {
+			return i, nil
+		}
+		r -= w
+	}
+	return len(weights) - 1, nil
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+)
+
+func TestRewardShares(t *testing.T) {
+	contributions := []contribution{{damage: 2}, {damage: 6, lastHit: true}, {damage: 2}}
+
+	cases := []struct {
+		strategy string
+		total    int
+		shares   []int
+	}{
+		// the points left by the integer division go to the largest remainders, then to the biggest damage dealers
+		{db.SplitEven, 10, []int{3, 4, 3}},
+		{db.SplitEven, 2, []int{1, 1, 0}},
+		{db.SplitProportional, 10, []int{2, 6, 2}},
+		{db.SplitProportional, 7, []int{2, 4, 1}},
+		{db.SplitLastHit, 100, []int{25, 50, 25}},
+		{db.SplitLastHit, 10, []int{3, 5, 2}},
+		{db.SplitEven, 0, []int{0, 0, 0}},
+	}
+	for _, c := range cases {
+		shares := rewardShares(c.strategy, c.total, contributions)
+		if !reflect.DeepEqual(shares, c.shares) {
+			t.Errorf("%s of %d: expected %v, got %v", c.strategy, c.total, c.shares, shares)
+		}
+	}
+
+	// without any damage recorded, proportional falls back to even shares
+	if shares := rewardShares(db.SplitProportional, 4, make([]contribution, 2)); !reflect.DeepEqual(shares, []int{2, 2}) {
+		t.Errorf("expected even shares, got %v", shares)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(11, "!join_adventure mage")
+
+	expectContains(t, h.sayOne(testGameMaster, "!split"), "The rewards of the fights are shared evenly.")
+	expectContains(t, h.sayOne(10, "!split proportional"), "Vous n'êtes pas maître du jeu")
+	expectContains(t, h.sayOne(testGameMaster, "!split proportional"), "now shared along the damage dealt")
+
+	h.sayOne(testGameMaster, "!spawn Ogre_100_0_0_0_5")
+	victory := ""
+	for turn := 0; turn < 100 && victory == ""; turn++ {
+		report := h.sayOne(uint(10+turn%2), "!hit")
+		if strings.Contains(report, "L'adversaire est vaincu !") {
+			victory = report
+		}
+	}
+	if victory == "" {
+		t.Fatal("the ogre never died")
+	}
+
+	monster, err := h.store.FetchLastDefeatedMonster(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	participations, err := h.store.FetchParticipations(&monster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, p := range participations {
+		total += p.Damage
+	}
+	if total != monster.GetMaxHP() {
+		t.Errorf("expected the damage to add up to %d HP, got %+v", monster.GetMaxHP(), participations)
+	}
+
+	experience := 0
+	for _, id := range []uint{10, 11} {
+		c := h.character(id)
+		experience += c.Experience
+		for _, p := range participations {
+			// the shares follow the damage, give or take the point left by the integer division
+			if expected := 100 * p.Damage / total; p.CharacterID == c.ID && c.Experience != expected && c.Experience != expected+1 {
+				t.Errorf("expected %d XP for %d damage, got %d", expected, p.Damage, c.Experience)
+			}
+		}
+	}
+	if experience != 100 {
+		t.Errorf("expected the 100 XP to be shared without loss, got %d", experience)
+	}
+
+	// the strategy is saved with the campaign
+	h.bot.campaigns = campaignCache{}
+	expectContains(t, h.sayOne(testGameMaster, "!split"), "shared along the damage dealt")
+	expectContains(t, h.sayOne(testGameMaster, "!split last_hit"), "with a 25% bonus for the final blow")
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"testing"
+)
+
+func TestGoldOnVictory(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+	h.sayOne(11, "!join_adventure")
+	h.sayOne(testGameMaster, "!spawn Chest_0_0_0_1_5_31")
+
+	report := ""
+	for turn := 0; turn < 20; turn++ {
+		report = h.sayOne(uint(10+turn%2), "!hit")
+		if h.character(10).Gold > 0 {
+			break
+		}
+	}
+	expectContains(t, report, "Le combat rapporte 0 point d'expérience et 31 pièces d'or partagés entre :")
+
+	// the odd piece is not lost, it goes to the biggest damage dealer: <@11> deals 8 damage to the 7 of <@10>
+	for id, want := range map[uint]int{10: 15, 11: 16} {
+		if c := h.character(id); c.Gold != want {
+			t.Errorf("expected %d gold for %d, got %d", want, id, c.Gold)
+		}
+	}
+	expectContains(t, report, "- <@10> (+0 XP, +15 po)\n")
+	expectContains(t, report, "- <@11> (+0 XP, +16 po)\n")
+	expectContains(t, h.sayOne(10, "!character"), "Or : 15\n")
+}
+
+func TestShop(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+
+	c := h.character(10)
+	c.Gold = 30
+	if err := h.store.SaveCharacter(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	expectContains(t, h.sayOne(10, "!shop"), "La boutique est vide.")
+	expectContains(t, h.sayOne(10, "!stock potion 5"), "Vous n'êtes pas maître du jeu")
+	expectContains(t, h.sayOne(testGameMaster, "!stock potion"), "Bad arguments")
+	expectContains(t, h.sayOne(testGameMaster, "!stock bow 5"), "Unknown item")
+	expectContains(t, h.sayOne(testGameMaster, "!stock potion 5"), "Potion on sale for 5 gold")
+	expectContains(t, h.sayOne(testGameMaster, "!stock Sword 12 1"), "1x Sword on sale for 12 gold")
+
+	shop := h.sayOne(10, "!shop")
+	expectContains(t, shop, "- Potion : 5 po :")
+	expectContains(t, shop, "- Sword : 12 po (1 en stock) : +3 attaque\n")
+
+	expectContains(t, h.sayOne(10, "!buy shield"), "Le marchand ne fait pas commerce de Shield.")
+	expectContains(t, h.sayOne(10, "!buy sword 2"), "Il n'y a plus assez de Sword en stock.")
+	expectContains(t, h.sayOne(10, "!buy sword"), "<@10> achète 1x Sword pour 12 po.")
+	expectContains(t, h.sayOne(10, "!buy sword"), "Il n'y a plus assez de Sword en stock.")
+	expectContains(t, h.sayOne(10, "!buy potion 4"), "Vous n'avez pas assez d'or.")
+	expectContains(t, h.sayOne(10, "!buy potion 3"), "<@10> achète 3x Potion pour 15 po.")
+
+	if c := h.character(10); c.Gold != 3 {
+		t.Errorf("expected 3 gold left, got %d", c.Gold)
+	}
+
+	expectContains(t, h.sayOne(10, "!sell sword 2"), "Vous n'avez pas assez de Sword.")
+	expectContains(t, h.sayOne(10, "!sell sword"), "<@10> vend 1x Sword pour 6 po.")
+	expectContains(t, h.sayOne(10, "!shop"), "- Sword : 12 po (1 en stock)")
+
+	expectContains(t, h.sayOne(testGameMaster, "!unstock sword"), "Sword removed from the shop")
+	expectContains(t, h.sayOne(testGameMaster, "!unstock sword"), "Sword is not on sale")
+	expectContains(t, h.sayOne(10, "!buy sword"), "Le marchand ne fait pas commerce de Sword.")
+
+	inventory := h.sayOne(10, "!inventory")
+	expectContains(t, inventory, "- Potion x3")
+	if c := h.character(10); c.Gold != 9 {
+		t.Errorf("expected 9 gold left, got %d", c.Gold)
+	}
+}
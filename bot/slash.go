@@ -0,0 +1,280 @@
+package bot
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/rs/zerolog/log"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+)
+
+// Discord limits the descriptions of the slash commands and of their options
+const maxSlashDescription = 100
+
+// discordLocales are the locales of the Discord clients showing each locale of the catalog
+var discordLocales = map[string][]discordgo.Locale{ //nolint:gochecknoglobals
+	"fr": {discordgo.French},
+	"en": {discordgo.EnglishUS, discordgo.EnglishGB},
+}
+
+// CommandAPI publishes the slash commands, implemented by *discordgo.Session
+type CommandAPI interface {
+	ApplicationCommands(appID string, guildID string) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandBulkOverwrite(appID string, guildID string,
+		commands []*discordgo.ApplicationCommand) ([]*discordgo.ApplicationCommand, error)
+}
+
+// SyncCommands publishes the registry as slash commands of the application, when they changed.
+// They are published on a single guild when guildID is set, on every guild otherwise.
+func (b *Bot) SyncCommands(api CommandAPI, appID string, guildID string) error {
+	wanted := slashCommands(b.messages)
+	if s, ok := api.(*discordgo.Session); ok {
+		api = localizedCommands{s}
+	}
+
+	published, err := api.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return err
+	}
+	if sameCommands(published, wanted) {
+		log.Debug().Int("commands", len(wanted)).Msg("slash commands are up to date")
+		return nil
+	}
+
+	if _, err := api.ApplicationCommandBulkOverwrite(appID, guildID, wanted); err != nil {
+		return err
+	}
+	log.Info().Int("commands", len(wanted)).Msg("slash commands published")
+	return nil
+}
+
+// localizedCommands reads the published commands of a Discord session with their translations:
+// discordgo only reads them in the locale of the bot, and they would never look up to date
+type localizedCommands struct {
+	*discordgo.Session
+}
+
+// ApplicationCommands lists the published commands, with all their translations
+func (s localizedCommands) ApplicationCommands(appID string, guildID string) ([]*discordgo.ApplicationCommand, error) {
+	endpoint := discordgo.EndpointApplicationGlobalCommands(appID)
+	if guildID != "" {
+		endpoint = discordgo.EndpointApplicationGuildCommands(appID, guildID)
+	}
+
+	body, err := s.RequestWithBucketID("GET", endpoint+"?with_localizations=true", nil, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := []*discordgo.ApplicationCommand{}
+	if err := json.Unmarshal(body, &cmds); err != nil {
+		return nil, err
+	}
+	return cmds, nil
+}
+
+// slashCommands defines the slash commands along the registry, without the aliases.
+// The descriptions are in the default locale, translated for the Discord clients in the other locales.
+func slashCommands(messages *i18n.Catalog) []*discordgo.ApplicationCommand {
+	defs := []*discordgo.ApplicationCommand{}
+	for _, cmd := range commands {
+		if !cmd.TextOnly {
+			defs = append(defs, slashCommand(messages, cmd))
+		}
+	}
+	return defs
+}
+
+// localizations translates a description for the Discord clients not in the default locale
+func localizations(messages *i18n.Catalog, describe func(tr i18n.Translator) string) map[discordgo.Locale]string {
+	def := describe(messages.Translator())
+
+	translated := map[discordgo.Locale]string{}
+	for _, locale := range messages.Locales() {
+		text := truncate(describe(messages.Translator(locale)), maxSlashDescription)
+		if text == truncate(def, maxSlashDescription) {
+			continue
+		}
+		for _, discordLocale := range discordLocales[locale] {
+			translated[discordLocale] = text
+		}
+	}
+	if len(translated) == 0 {
+		return nil
+	}
+	return translated
+}
+
+func slashCommand(messages *i18n.Catalog, cmd *_Command) *discordgo.ApplicationCommand {
+	options := []*discordgo.ApplicationCommandOption{}
+	for _, arg := range slashArgs(cmd) {
+		options = append(options, slashOption(messages, &arg))
+	}
+	// Discord wants the required options first
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Required && !options[j].Required
+	})
+
+	def := &discordgo.ApplicationCommand{
+		Type:        discordgo.ChatApplicationCommand,
+		Name:        cmd.Name,
+		Description: truncate(cmd.description(messages.Translator()), maxSlashDescription),
+		Options:     options,
+	}
+	if translated := localizations(messages, cmd.description); translated != nil {
+		def.DescriptionLocalizations = &translated
+	}
+	return def
+}
+
+// slashArgs are the arguments of the command, then its key=value options
+func slashArgs(cmd *_Command) []_Arg {
+	args := append([]_Arg{}, cmd.Args...)
+	for _, option := range cmd.Options {
+		option.Optional = true
+		args = append(args, option)
+	}
+	return args
+}
+
+// slashOption types an argument, described by its label
+func slashOption(messages *i18n.Catalog, arg *_Arg) *discordgo.ApplicationCommandOption {
+	label := func(tr i18n.Translator) string { return argLabel(tr, arg.Name) }
+	option := &discordgo.ApplicationCommandOption{
+		Type:                     discordgo.ApplicationCommandOptionString,
+		Name:                     slashName(arg),
+		Description:              truncate(label(messages.Translator()), maxSlashDescription),
+		DescriptionLocalizations: localizations(messages, label),
+		Required:                 !arg.Optional,
+	}
+
+	switch arg.Kind {
+	case argInt:
+		option.Type = discordgo.ApplicationCommandOptionInteger
+		if arg.Range != nil {
+			min := float64(arg.Range.Min)
+			option.MinValue = &min
+			if arg.Range.Max != math.MaxInt32 {
+				option.MaxValue = float64(arg.Range.Max)
+			}
+		}
+	case argMention:
+		option.Type = discordgo.ApplicationCommandOptionUser
+	case argFlag:
+		option.Type = discordgo.ApplicationCommandOptionBoolean
+	case argEnum:
+		for _, choice := range arg.Choices {
+			option.Choices = append(option.Choices, &discordgo.ApplicationCommandOptionChoice{Name: choice, Value: choice})
+		}
+	}
+	return option
+}
+
+// slashName follows the naming rules of the options: lower case, without spaces nor symbols
+func slashName(arg *_Arg) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '@':
+			return -1
+		case ' ', '|':
+			return '_'
+		}
+		return r
+	}, strings.ToLower(arg.Name))
+}
+
+func truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max-1]) + "…"
+}
+
+// sameCommands compares the published commands with the definitions, ignoring the IDs given by Discord
+func sameCommands(published []*discordgo.ApplicationCommand, wanted []*discordgo.ApplicationCommand) bool {
+	if len(published) != len(wanted) {
+		return false
+	}
+
+	byName := map[string]*discordgo.ApplicationCommand{}
+	for _, cmd := range published {
+		byName[cmd.Name] = cmd
+	}
+	for _, cmd := range wanted {
+		p, ok := byName[cmd.Name]
+		if !ok || p.Description != cmd.Description {
+			return false
+		}
+
+		publishedLocalizations, err := json.Marshal(p.DescriptionLocalizations)
+		if err != nil {
+			return false
+		}
+		wantedLocalizations, err := json.Marshal(cmd.DescriptionLocalizations)
+		if err != nil || string(publishedLocalizations) != string(wantedLocalizations) {
+			return false
+		}
+
+		publishedOptions, err := json.Marshal(p.Options)
+		if err != nil {
+			return false
+		}
+		wantedOptions, err := json.Marshal(cmd.Options)
+		if err != nil || string(publishedOptions) != string(wantedOptions) {
+			return false
+		}
+	}
+	return true
+}
+
+// optionArgs reads the options of a slash command along the schema of the command
+func optionArgs(cmd *_Command, options map[string]string) (_Args, error) {
+	parsed := _Args{values: map[string]interface{}{}}
+
+	for _, arg := range slashArgs(cmd) {
+		value, ok := options[slashName(&arg)]
+		if !ok {
+			if !arg.Optional {
+				return parsed, &argError{arg: arg.Name, reason: "missing"}
+			}
+			continue
+		}
+		if err := parsed.set(&arg, value); err != nil {
+			return parsed, err
+		}
+	}
+	return parsed, nil
+}
+
+// slashText writes the options of a slash command as the text of a chat command, for the handlers reading it
+func slashText(cmd *_Command, options map[string]string) string {
+	values := []string{}
+	for _, arg := range cmd.Args {
+		if value, ok := options[slashName(&arg)]; ok {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+// slashValue writes the value of an option as typed in a chat command
+func slashValue(option *discordgo.ApplicationCommandInteractionDataOption) string {
+	switch option.Type {
+	case discordgo.ApplicationCommandOptionInteger:
+		return strconv.FormatInt(option.IntValue(), 10)
+	case discordgo.ApplicationCommandOptionUser:
+		return "<@" + option.Value.(string) + ">"
+	case discordgo.ApplicationCommandOptionBoolean:
+		return strconv.FormatBool(option.BoolValue())
+	}
+	if s, ok := option.Value.(string); ok {
+		return s
+	}
+	return ""
+}
@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// fakeCommandAPI keeps the published slash commands in memory
+type fakeCommandAPI struct {
+	published  []*discordgo.ApplicationCommand
+	overwrites int
+}
+
+func (f *fakeCommandAPI) ApplicationCommands(appID string, guildID string) ([]*discordgo.ApplicationCommand, error) {
+	return f.published, nil
+}
+
+func (f *fakeCommandAPI) ApplicationCommandBulkOverwrite(appID string, guildID string,
+	commands []*discordgo.ApplicationCommand) ([]*discordgo.ApplicationCommand, error) {
+	f.overwrites++
+
+	// Discord answers with the IDs, going through JSON like the real API
+	raw, err := json.Marshal(commands)
+	if err != nil {
+		return nil, err
+	}
+	published := []*discordgo.ApplicationCommand{}
+	if err := json.Unmarshal(raw, &published); err != nil {
+		return nil, err
+	}
+	for _, cmd := range published {
+		cmd.ID = "id-" + cmd.Name
+		cmd.ApplicationID = appID
+	}
+	f.published = published
+	return published, nil
+}
+
+// fakeInteraction is a Transport recording the answers to a slash command, private ones apart
+type fakeInteraction struct {
+	*fakeSession
+	private []string
+}
+
+func (f *fakeInteraction) SendPrivate(channelID string, message string) error {
+	f.private = append(f.private, message)
+	return nil
+}
+
+// slash runs a slash command as authorID on the adventure channel, and returns the public and private answers
+func (h *harness) slash(authorID uint, command string, options map[string]string) ([]string, []string) {
+	h.t.Helper()
+
+	cmd, ok := router[command]
+	if !ok {
+		h.t.Fatalf("unknown command %q", command)
+	}
+
+	req := NewRequest("/"+command, authorID, "player", testChannel)
+	req.GuildID = testGuild
+	req.Command = command
+	req.Options = options
+	req.Text = slashText(cmd, options)
+	req.Args = strings.Fields(req.Text)
+
+	t := &fakeInteraction{fakeSession: &fakeSession{}}
+	h.bot.Dispatch(t, req)
+
+	public := []string{}
+	for _, msg := range t.sent {
+		public = append(public, msg.Message)
+	}
+	return public, t.private
+}
+
+func TestSlashCommandDefinitions(t *testing.T) {
+	validName := regexp.MustCompile(`^[-_\p{Ll}\p{N}]{1,32}$`)
+
+	defs := slashCommands(newHarness(t).bot.messages)
+	for _, def := range defs {
+		if !validName.MatchString(def.Name) || def.Description == "" || len([]rune(def.Description)) > 100 {
+			t.Errorf("invalid command %+v", def)
+		}
+		if def.DescriptionLocalizations == nil || (*def.DescriptionLocalizations)[discordgo.EnglishUS] == "" {
+			t.Errorf("%s: expected an English description", def.Name)
+		}
+
+		names := map[string]bool{}
+		optional := false
+		for _, option := range def.Options {
+			if !validName.MatchString(option.Name) || names[option.Name] || option.Description == "" {
+				t.Errorf("%s: invalid option %+v", def.Name, option)
+			}
+			if option.Required && optional {
+				t.Errorf("%s: required option %q after an optional one", def.Name, option.Name)
+			}
+			names[option.Name] = true
+			optional = optional || !option.Required
+		}
+
+		if def.Name == "str" {
+			t.Error("!str is published as /stats")
+		}
+		if def.Name == "stats" {
+			if len(def.Options) != 3 || len(def.Options[0].Choices) != 4 ||
+				def.Options[1].Type != discordgo.ApplicationCommandOptionInteger || *def.Options[1].MinValue != 1 ||
+				def.Options[2].Type != discordgo.ApplicationCommandOptionBoolean || def.Options[2].Required {
+				t.Errorf("unexpected /stats options %+v", def.Options)
+			}
+		}
+	}
+}
+
+func TestSyncCommands(t *testing.T) {
+	h := newHarness(t)
+	api := &fakeCommandAPI{}
+
+	if err := h.bot.SyncCommands(api, "app", ""); err != nil {
+		t.Fatal(err)
+	}
+	if api.overwrites != 1 || len(api.published) != len(slashCommands(h.bot.messages)) {
+		t.Fatalf("expected the commands to be published, got %d overwrites", api.overwrites)
+	}
+
+	// nothing changed, nothing is published
+	if err := h.bot.SyncCommands(api, "app", ""); err != nil {
+		t.Fatal(err)
+	}
+	if api.overwrites != 1 {
+		t.Errorf("expected no new overwrite, got %d", api.overwrites)
+	}
+
+	api.published[0].Description = "outdated"
+	if err := h.bot.SyncCommands(api, "app", ""); err != nil {
+		t.Fatal(err)
+	}
+	if api.overwrites != 2 {
+		t.Errorf("expected the outdated commands to be overwritten, got %d overwrites", api.overwrites)
+	}
+
+	// so are the outdated translations
+	(*api.published[0].DescriptionLocalizations)[discordgo.EnglishUS] = "outdated"
+	if err := h.bot.SyncCommands(api, "app", ""); err != nil {
+		t.Fatal(err)
+	}
+	if api.overwrites != 3 {
+		t.Errorf("expected the outdated translations to be overwritten, got %d overwrites", api.overwrites)
+	}
+}
+
+func TestSlashCommands(t *testing.T) {
+	h := newHarness(t)
+
+	public, _ := h.slash(10, "join_adventure", map[string]string{"class": "mage"})
+	expectContains(t, strings.Join(public, "\n"), "<@10> a rejoint l'aventure en tant que Mage !")
+
+	public, _ = h.slash(10, "stats", map[string]string{"stat": "wis", "points": "2"})
+	expectContains(t, strings.Join(public, "\n"), "Répartition effectuée !")
+	if c := h.character(10); c.Wisdom != 3 {
+		t.Errorf("expected 3 wisdom, got %d", c.Wisdom)
+	}
+
+	// personal answers and errors are only shown to the author
+	public, private := h.slash(10, "character", map[string]string{})
+	if len(public) != 0 || len(private) != 1 {
+		t.Fatalf("expected a private character sheet, got %q and %q", public, private)
+	}
+	expectContains(t, private[0], "<@10> (Mage)")
+
+	_, private = h.slash(10, "stats", map[string]string{"stat": "con", "points": "9"})
+	expectContains(t, strings.Join(private, "\n"), "Répartition impossible : il ne vous reste que 3 points.")
+
+	public, _ = h.slash(10, "stats", map[string]string{"stat": "con", "points": "1", "dry-run": "true"})
+	expectContains(t, strings.Join(public, "\n"), "Constitution : 1 → 2")
+	if c := h.character(10); c.Constitution != 1 || c.SkillPoints != 3 {
+		t.Errorf("the preview spent points: %+v", c)
+	}
+
+	_, private = h.slash(10, "stats", map[string]string{"points": "1"})
+	expectContains(t, strings.Join(private, "\n"), "(caractéristique manquant)")
+
+	public, _ = h.slash(testGameMaster, "spawn", map[string]string{"monsters": "Rat_10_1_1_1_1"})
+	expectContains(t, strings.Join(public, "\n"), "Monster spawned (#1)")
+	public, _ = h.slash(testGameMaster, "revive", map[string]string{"player": "<@10>"})
+	expectContains(t, strings.Join(public, "\n"), "<@10> is revived")
+}
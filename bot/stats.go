@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+	"github.com/vincent-heng/discord-airpgbot/bot/util"
+)
+
+// statOptions preview the stat commands with --dry-run
+var statOptions = []_Arg{{Name: "dry-run", Kind: argFlag}} //nolint:gochecknoglobals
+
+// upStatsErr answers the errors of the stat commands
+func (b *Bot) upStatsErr(req *Request, err error, c *db.Character, stat string) _Response {
+	switch {
+	case errors.Is(err, db.ErrNotEnoughSkillPoints):
+		return simpleErr(err, req.N("stats.not_enough", c.SkillPoints))
+	case errors.Is(err, db.ErrStatCapped):
+		return simpleErr(err, req.T("stats.capped", i18n.Vars{
+			"Stat":  req.T("sheet." + stat),
+			"Cap":   b.progression.StatCap(c.Level),
+			"Level": c.Level,
+		}))
+	}
+	return simpleErr(fmt.Errorf("cannot upgrade stat: %w", err), req.T("stats.error"))
+}
+
+// previewStats shows the character after spending the points, without saving it
+func (b *Bot) previewStats(req *Request, c *db.Character, stat string) _Response {
+	preview := *c
+	if err := preview.AddStat(b.progression, stat, req.args.Int("points")); err != nil {
+		return b.upStatsErr(req, err, c, stat)
+	}
+
+	weaponBonus, _, err := b.equipmentBonus(b.db, c.ID)
+	if err != nil {
+		return simpleErr(fmt.Errorf("cannot fetch equipment: %w", err), req.T("stats.error"))
+	}
+	min, max := damageRange(&preview, weaponBonus)
+
+	from, _ := c.Stat(stat)
+	to, _ := preview.Stat(stat)
+	return simpleResponse(req.T("stats.preview", i18n.Vars{
+		"Stat":         req.T("sheet." + stat),
+		"From":         *from,
+		"To":           *to,
+		"Strength":     preview.Strength,
+		"Agility":      preview.Agility,
+		"Wisdom":       preview.Wisdom,
+		"Constitution": preview.Constitution,
+		"MaxHP":        preview.GetMaxHP(b.progression),
+		"Min":          min,
+		"Max":          max,
+		"Left":         preview.SkillPoints,
+	}))
+}
+
+// respec refunds the skill points of the character, for the price and the cooldown of the progression
+func (b *Bot) respec(campaignID uint, userID uint) (db.Character, int, error) {
+	tx := b.db.Begin()
+	defer tx.Rollback()
+
+	character, err := tx.FetchCharacterInfo(campaignID, userID)
+	if err != nil {
+		return character, 0, err
+	}
+
+	monsters, err := tx.FetchMonsters(campaignID)
+	if err != nil {
+		return character, 0, err
+	}
+	if len(monsters) > 0 {
+		return character, 0, errFightInProgress
+	}
+
+	now := b.now()
+	if next := character.RespecAt.Add(b.progression.RespecCooldown()); !character.RespecAt.IsZero() && now.Before(next) {
+		return character, 0, fmt.Errorf("%v left: %w", next.Sub(now), errRespecCooldown)
+	}
+
+	before := character.SkillPoints
+	character.Regenerate(b.progression, now)
+	if err := character.Respec(b.progression); err != nil {
+		return character, 0, err
+	}
+	if b.progression.RespecGold > 0 {
+		err := tx.AddGold(character.ID, -b.progression.RespecGold)
+		if errors.Is(err, db.ErrNotEnoughGold) {
+			return character, 0, errRespecGold
+		}
+		if err != nil {
+			return character, 0, err
+		}
+		character.Gold -= b.progression.RespecGold
+	}
+	character.RespecAt = now
+
+	columns := append([]string{"respec_at"}, db.RegenColumns...)
+	if err := tx.UpdateCharacter(&character, append(columns, db.StatColumns...)...); err != nil {
+		return character, 0, err
+	}
+	return character, character.SkillPoints - before, tx.Commit()
+}
+
+// respecCmd gives back the skill points spent in the stats
+func (b *Bot) respecCmd(req *Request) _Response {
+	c, refund, err := b.respec(req.Campaign.ID, req.AuthorID)
+	cost := i18n.Vars{"Gold": req.N("victory.gold", b.progression.RespecGold)}
+	switch {
+	case errors.Is(err, db.ErrNothingToRefund):
+		return simpleErr(err, req.T("respec.nothing"))
+	case errors.Is(err, errFightInProgress):
+		return simpleErr(err, req.T("respec.fight"))
+	case errors.Is(err, errRespecGold):
+		return simpleErr(err, req.T("respec.not_enough_gold", cost))
+	case errors.Is(err, errRespecCooldown):
+		wait := c.RespecAt.Add(b.progression.RespecCooldown()).Sub(b.now()).Round(time.Minute)
+		if wait < time.Minute {
+			wait = time.Minute
+		}
+		return simpleErr(err, req.T("respec.cooldown", i18n.Vars{
+			"Hours":   int(wait.Hours()),
+			"Minutes": int(wait.Minutes()) % 60,
+		}))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return simpleErr(err, req.T("character.missing"))
+	case err != nil:
+		return simpleErr(fmt.Errorf("cannot respec: %w", err), req.T("respec.error"))
+	}
+
+	report := req.N("respec.done", refund, i18n.Vars{"Player": util.DiscordIDToText(c.UserID)})
+	if b.progression.RespecGold > 0 {
+		report += " " + req.T("respec.cost", cost)
+	}
+	return simpleResponse(report)
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/progression"
+)
+
+func TestStatsPreview(t *testing.T) {
+	h := newHarness(t)
+	h.sayOne(10, "!join_adventure")
+
+	preview := h.sayOne(10, "!str 3 --dry-run")
+	expectContains(t, preview, "Aperçu, rien n'est encore dépensé :\nForce : 1 → 4\n"+
+		"Force 4, Agilité 1, Sagesse 1, Constitution 1\nPV max : 12\nDégâts : 4 à 6, avant la défense de l'adversaire\n"+
+		"Points restants : 2")
+	if c := h.character(10); c.Strength != 1 || c.SkillPoints != 5 {
+		t.Errorf("the preview spent points: %+v", c)
+	}
+
+	expectContains(t, h.sayOne(10, "!stats con 2 --dry-run"), "PV max : 14")
+	expectContains(t, h.sayOne(10, "!wis 6 --dry-run"), "il ne vous reste que 5 points")
+
+	// 8 at level 1 with the default progression
+	c := h.character(10)
+	c.SkillPoints = 20
+	if err := h.store.SaveCharacter(&c); err != nil {
+		t.Fatal(err)
+	}
+	expectContains(t, h.sayOne(10, "!agi 8 --dry-run"), "Répartition impossible : Agilité est limitée à 8 au niveau 1.")
+	expectContains(t, h.sayOne(10, "!agi 8"), "Répartition impossible : Agilité est limitée à 8 au niveau 1.")
+	expectContains(t, h.sayOne(10, "!agi 7"), "Répartition effectuée !")
+}
+
+func TestRespec(t *testing.T) {
+	h := newHarness(t)
+	curve := progression.Default()
+	curve.RespecGold = 10
+	curve.RespecCooldownHours = 24
+	h.bot.progression = curve
+
+	h.sayOne(10, "!join_adventure")
+	expectContains(t, h.sayOne(10, "!respec"), "Vous n'avez aucun point à récupérer.")
+
+	h.sayOne(10, "!str 2")
+	h.sayOne(10, "!con 3")
+	expectContains(t, h.sayOne(10, "!respec"), "Il faut 10 pièces d'or pour répartir à nouveau vos points.")
+
+	c := h.character(10)
+	c.Gold = 25
+	if err := h.store.SaveCharacter(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	h.sayOne(testGameMaster, "!spawn Rat_0_0_0_0_-9")
+	expectContains(t, h.sayOne(10, "!respec"), "Impossible de répartir à nouveau vos points en plein combat !")
+	expectContains(t, h.sayOne(10, "!hit"), "L'adversaire est vaincu !")
+
+	expectContains(t, h.sayOne(10, "!respec"), "<@10> récupère 5 points à répartir. Il vous en coûte 10 pièces d'or.")
+	c = h.character(10)
+	if c.Strength != db.BaseStat || c.Constitution != db.BaseStat || c.SkillPoints != 5 || c.Gold != 15 ||
+		c.CurrentHp > c.GetMaxHP(curve) {
+		t.Errorf("unexpected character after respec %+v", c)
+	}
+
+	h.sayOne(10, "!agi 1")
+	h.clock = h.clock.Add(90 * time.Minute)
+	expectContains(t, h.sayOne(10, "!respec"), "Vous pourrez répartir à nouveau vos points dans 22 h 30 min.")
+
+	h.clock = h.clock.Add(23 * time.Hour)
+	expectContains(t, h.sayOne(10, "!respec"), "<@10> récupère 1 point à répartir.")
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/vincent-heng/discord-airpgbot/bot/db"
+	"github.com/vincent-heng/discord-airpgbot/bot/i18n"
+)
+
+// Request is a transport-agnostic command invocation
+type Request struct {
+	AuthorID   uint
+	AuthorName string
+	// AuthorAvatar is the URL of the picture of the author, if the transport knows it
+	AuthorAvatar string
+	// RoleIDs are the Discord roles of the author on the guild
+	RoleIDs []string
+	// GuildID is the server the request comes from, empty for direct messages
+	GuildID   string
+	ChannelID string
+	// BotID is the user ID of the bot, to invoke the commands by mentioning it
+	BotID string
+	// Content is the raw message
+	Content string
+
+	// Command, Args and Text are parsed by Dispatch, along the prefix of the campaign
+	Command string
+	Args    []string
+	// Text is the raw content following the command name
+	Text string
+	// Options are the typed arguments of a slash command, by name, nil for chat messages
+	Options map[string]string
+
+	// Campaign is resolved by Dispatch, from the guild or the last campaign played by the author
+	Campaign *db.Campaign
+	// Player holds the settings of the author, loaded by Dispatch
+	Player *db.Player
+	// args are parsed by Dispatch, along the schema of the command
+	args _Args
+	// tr writes the answers in the language of the author, or of the campaign
+	tr i18n.Translator
+}
+
+// Transport delivers the bot answers back to the players (Discord, console...)
+type Transport interface {
+	Send(channelID string, message string) error
+}
+
+// privateTransport can answer the author only, like the ephemeral replies to Discord slash commands
+type privateTransport interface {
+	Transport
+	SendPrivate(channelID string, message string) error
+}
+
+// NewRequest wraps a chat message, the command is parsed once the campaign is known
+func NewRequest(content string, authorID uint, authorName string, channelID string) *Request {
+	return &Request{
+		AuthorID:   authorID,
+		AuthorName: authorName,
+		ChannelID:  channelID,
+		Content:    content,
+	}
+}
+
+// parse reads the command of the message, starting with prefix or with a mention of the bot (@bot hit).
+// It returns false when the message is not a command.
+func (req *Request) parse(prefix string) bool {
+	content := strings.TrimSpace(req.Content)
+
+	rest, mentioned := trimMention(content, req.BotID)
+	switch {
+	case mentioned:
+		// @bot !hit works as well
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), prefix)
+	case prefix != "" && strings.HasPrefix(content, prefix):
+		rest = content[len(prefix):]
+	default:
+		return false
+	}
+
+	// the command sticks to the prefix: "! hit" is not a command
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || !strings.HasPrefix(rest, fields[0]) {
+		return false
+	}
+
+	req.Command = fields[0]
+	req.Args = fields[1:]
+	req.Text = strings.TrimSpace(strings.TrimPrefix(rest, fields[0]))
+	return true
+}
+
+// T writes a message of the catalog in the language of the author
+func (req *Request) T(key string, vars ...i18n.Vars) string {
+	return req.tr.T(key, vars...)
+}
+
+// N writes a message varying with count in the language of the author
+func (req *Request) N(key string, count int, vars ...i18n.Vars) string {
+	return req.tr.N(key, count, vars...)
+}
+
+// readArgs reads the arguments of the command from the text, or from the options of a slash command
+func (req *Request) readArgs(cmd *_Command) (_Args, error) {
+	if req.Options == nil {
+		return parseArgs(cmd.Args, cmd.Options, req.Text)
+	}
+	return optionArgs(cmd, req.Options)
+}
+
+// trimMention removes the mention of the bot starting the content
+func trimMention(content string, botID string) (string, bool) {
+	if botID == "" {
+		return content, false
+	}
+
+	for _, mention := range []string{"<@" + botID + ">", "<@!" + botID + ">"} {
+		if strings.HasPrefix(content, mention) {
+			return content[len(mention):], true
+		}
+	}
+	return content, false
+}
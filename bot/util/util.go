@@ -2,21 +2,12 @@ package util
 
 import (
 	"bufio"
-	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 )
 
-func fileExists(fileName string) bool {
-	_, err := os.Stat(fileName)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false
-		}
-	}
-	return true
-}
-
+// GetChannelID returns the adventure channel saved in a file before campaigns, empty if there is none
 func GetChannelID() (string, error) {
 	return readFile("current_channel.txt")
 }
@@ -26,6 +17,7 @@ func readFile(fileName string) (string, error) {
 	if err != nil {
 		return "", nil
 	}
+	defer file.Close()
 	scanner := bufio.NewScanner(file)
 
 	success := scanner.Scan()
@@ -45,25 +37,49 @@ func DiscordIDToText(userID uint) string {
 	return "<@" + strconv.FormatUint(uint64(userID), 10) + ">"
 }
 
-func SetAdventureChannel(channelID string) error {
-	fileName := "current_channel.txt"
-	if !fileExists(fileName) {
-		file, err := os.Create(fileName)
-		if err != nil {
-			return err
-		}
-		file.Close()
-	} else {
-		err := os.Truncate(fileName, 0)
-		if err != nil {
-			return err
-		}
+// RoleIDToText writes a Discord role mention
+func RoleIDToText(roleID string) string {
+	return "<@&" + roleID + ">"
+}
+
+// TextToRoleID parses a Discord role mention (<@&123>)
+func TextToRoleID(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "<@&") || !strings.HasSuffix(text, ">") {
+		return "", false
 	}
 
-	err := ioutil.WriteFile(fileName, []byte(channelID), 0666)
-	if err != nil {
-		return err
+	roleID := strings.TrimSuffix(strings.TrimPrefix(text, "<@&"), ">")
+	if _, err := strconv.ParseUint(roleID, 10, 64); err != nil {
+		return "", false
 	}
+	return roleID, true
+}
+
+// ChannelIDToText writes a channel mention
+func ChannelIDToText(channelID string) string {
+	return "<#" + channelID + ">"
+}
+
+// ProgressBar draws value out of max as a bar of width characters
+func ProgressBar(value int, max int, width int) string {
+	filled := 0
+	if max > 0 && value > 0 {
+		filled = (value*width + max - 1) / max
+	}
+	if filled > width {
+		filled = width
+	}
+
+	return "`" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "`"
+}
+
+// TextToDiscordID parses a user mention (<@123> or <@!123>) or a raw user ID
+func TextToDiscordID(text string) (uint, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(strings.TrimSuffix(text, ">"), "<@")
+	text = strings.TrimPrefix(text, "!")
 
-	return nil
+	id, err := strconv.ParseUint(text, 10, 64)
+	return uint(id), err
 }
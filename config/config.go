@@ -3,5 +3,22 @@ package config
 // Config filled from configuration file
 type Config struct {
 	DiscordBotKey string
-	GameMaster    uint
+	// GameMaster owns the bot, they are a game master of every campaign
+	GameMaster uint
+	// Storage is "postgres" (default) or "sqlite"
+	Storage string
+	// SQLiteFile is the sqlite database file, the database is kept in memory when empty
+	SQLiteFile string
+	// BestiaryDir holds the monster templates, "bestiary" by default
+	BestiaryDir string
+	// ItemsFile is the item catalog, "items.json" by default
+	ItemsFile string
+	// ProgressionFile is the leveling curve of the characters, "progression.json" by default
+	ProgressionFile string
+	// LocalesDir holds the message catalog, one <locale>.json file per language, "locales" by default
+	LocalesDir string
+	// Locale is the language of the campaigns until they choose one, "fr" by default
+	Locale string
+	// SlashCommandsGuild publishes the slash commands on this server only, instantly, instead of every server
+	SlashCommandsGuild string
 }
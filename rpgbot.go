@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
@@ -30,6 +31,9 @@ func loadConfiguration(configurationFile string) (config.Config, error) {
 }
 
 func main() {
+	consoleUser := flag.Uint("console", 0, "run a local REPL as the given user ID instead of connecting to Discord")
+	flag.Parse()
+
 	log.Info().Msg("Starting...")
 
 	// Configuration
@@ -38,25 +42,44 @@ func main() {
 		log.Fatal().Err(err).Msg("cannot load config file")
 	}
 
+	bot, err := bot.New(conf)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot init the bot")
+	}
+
+	// Local REPL
+	if *consoleUser != 0 {
+		if err := bot.RunConsole(os.Stdin, os.Stdout, *consoleUser); err != nil {
+			log.Fatal().Err(err).Msg("console stopped")
+		}
+		return
+	}
+
 	// Discord
 	dg, err := discordgo.New("Bot " + conf.DiscordBotKey)
 	if err != nil {
 		log.Fatal().Err(err).Msg("cannot create Discord session")
 	}
 
-	bot, err := bot.New(conf)
-	if err != nil {
-		log.Fatal().Err(err).Msg("cannot init the bot")
+	// before any message, so that the adventures are read once imported
+	if err := bot.ImportLegacyGame(dg); err != nil {
+		log.Error().Err(err).Msg("cannot import the game played before campaigns")
 	}
 
 	// Register the messageCreate func as a callback for MessageCreate events.
 	dg.AddHandler(bot.Handler)
+	// Slash commands run the same handlers
+	dg.AddHandler(bot.InteractionHandler)
 
 	// Open a websocket connection to Discord and begin listening.
 	if err := dg.Open(); err != nil {
 		log.Fatal().Err(err).Msg("cannot open discord connection")
 	}
 
+	if err := bot.SyncCommands(dg, dg.State.User.ID, conf.SlashCommandsGuild); err != nil {
+		log.Error().Err(err).Msg("cannot publish slash commands")
+	}
+
 	// Wait here until CTRL-C or other term signal is received.
 	log.Info().Msg("Bot is now running. Press CTRL-C to exit.")
 	sc := make(chan os.Signal, 1)